@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+type API struct {
+	db     *pgxpool.Pool
+	engine *gin.Engine
+	srv    *http.Server
+	logger *logrus.Entry
+}
+
+func New(db *pgxpool.Pool) *API {
+	return &API{
+		db:     db,
+		logger: logrus.WithField("module", "api"),
+	}
+}
+
+// Run starts the http server and blocks until the context is canceled or the server fails
+func (a *API) Run(ctx context.Context) error {
+	a.engine = gin.Default()
+
+	if config.Store.API.DevCors {
+		a.engine.Use(a.devCors())
+	}
+
+	a.setRoutes()
+
+	a.srv = &http.Server{
+		Addr:    ":" + config.Store.API.Port,
+		Handler: a.engine,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		a.logger.Infof("starting api on port %s", config.Store.API.Port)
+
+		err := a.srv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return a.Close()
+	}
+}
+
+func (a *API) Close() error {
+	if a.srv == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return a.srv.Shutdown(ctx)
+}
+
+func (a *API) devCors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", config.Store.API.DevCorsHost)
+		c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept")
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (a *API) setRoutes() {
+	a.engine.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": http.StatusOK})
+	})
+
+	v1 := a.engine.Group("/api")
+
+	sa := v1.Group("/smartalpha")
+	sa.GET("/pools", a.SmartAlphaPools)
+	sa.GET("/pools/:address/epochs", a.SmartAlphaPoolEpochs)
+	sa.GET("/transactions", a.SmartAlphaTransactions)
+	sa.GET("/users/:address/portfolio-value", a.SmartAlphaUserPortfolioValue)
+
+	sy := v1.Group("/smartyield")
+	sy.GET("/pools", a.SmartYieldPools)
+	sy.GET("/transactions", a.SmartYieldTransactions)
+	sy.GET("/users/:address/portfolio-value", a.SmartYieldUserPortfolioValue)
+
+	gov := v1.Group("/governance")
+	gov.GET("/proposals", a.GovernanceProposals)
+	gov.GET("/proposals/:proposalID", a.GovernanceProposal)
+	gov.GET("/proposals/:proposalID/votes", a.GovernanceProposalVotes)
+	gov.GET("/voters", a.GovernanceVoters)
+
+	barn := v1.Group("/barn")
+	barn.GET("/users", a.BarnUsers)
+
+	v1.GET("/notifications", a.Notifications)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type BarnUser struct {
+	Address     string          `json:"address"`
+	BondStaked  decimal.Decimal `json:"bondStaked"`
+	LockedUntil int64           `json:"lockedUntil"`
+}
+
+func (a *API) BarnUsers(c *gin.Context) {
+	page, limit, offset, err := getPagination(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	rows, err := a.db.Query(c, `
+		select user_address,
+			   coalesce(governance.balance_of(user_address), 0) as bond_staked,
+			   coalesce(( select locked_until
+						  from governance.barn_locks l
+						  where l.user_address = u.user_address
+						  order by included_in_block desc, log_index desc
+						  limit 1 ), 0)
+		from governance.barn_users u
+		order by bond_staked desc
+		limit $1 offset $2
+	`, limit, offset)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query barn users"))
+		return
+	}
+	defer rows.Close()
+
+	users := make([]BarnUser, 0)
+	for rows.Next() {
+		var u BarnUser
+
+		err := rows.Scan(&u.Address, &u.BondStaked, &u.LockedUntil)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan barn user"))
+			return
+		}
+
+		users = append(users, u)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read barn users"))
+		return
+	}
+
+	var count int64
+	err = a.db.QueryRow(c, `select count(*) from governance.barn_users`).Scan(&count)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not count barn users"))
+		return
+	}
+
+	OK(c, users, paginationMeta(page, limit, count))
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/barnbridge/meminero/types"
+)
+
+type Proposal struct {
+	ProposalID          int64                 `json:"proposalId"`
+	Proposer            string                `json:"proposer"`
+	Title               string                `json:"title"`
+	Description         string                `json:"description,omitempty"`
+	CreateTime          int64                 `json:"createTime"`
+	State               string                `json:"state"`
+	Targets             types.JSONStringArray `json:"targets,omitempty"`
+	Values              types.JSONStringArray `json:"values,omitempty"`
+	Signatures          types.JSONStringArray `json:"signatures,omitempty"`
+	Calldatas           types.JSONStringArray `json:"calldatas,omitempty"`
+	WarmUpDuration      int64                 `json:"warmUpDuration"`
+	ActiveDuration      int64                 `json:"activeDuration"`
+	QueueDuration       int64                 `json:"queueDuration"`
+	GracePeriodDuration int64                 `json:"gracePeriodDuration"`
+	AcceptanceThreshold int64                 `json:"acceptanceThreshold"`
+	MinQuorum           int64                 `json:"minQuorum"`
+	ForVotes            decimal.Decimal       `json:"forVotes"`
+	AgainstVotes        decimal.Decimal       `json:"againstVotes"`
+}
+
+type Vote struct {
+	UserID         string          `json:"address"`
+	Support        bool            `json:"support"`
+	BlockTimestamp int64           `json:"blockTimestamp"`
+	Power          decimal.Decimal `json:"power"`
+}
+
+type Voter struct {
+	Address             string          `json:"address"`
+	BondStaked          decimal.Decimal `json:"bondStaked"`
+	LockedUntil         int64           `json:"lockedUntil"`
+	DelegatedPower      decimal.Decimal `json:"delegatedPower"`
+	Votes               int64           `json:"votes"`
+	Proposals           int64           `json:"proposals"`
+	VotingPower         decimal.Decimal `json:"votingPower"`
+	HasActiveDelegation bool            `json:"hasActiveDelegation"`
+}
+
+const proposalColumns = `
+	proposal_id,
+	proposer,
+	title,
+	create_time,
+	governance.proposal_state(proposal_id),
+	coalesce(warm_up_duration, 0),
+	coalesce(active_duration, 0),
+	coalesce(queue_duration, 0),
+	coalesce(grace_period_duration, 0),
+	coalesce(acceptance_threshold, 0),
+	coalesce(min_quorum, 0),
+	coalesce(( select sum(power) from governance.proposal_votes(proposal_id) where support = true ), 0),
+	coalesce(( select sum(power) from governance.proposal_votes(proposal_id) where support = false ), 0)
+`
+
+func scanProposal(row pgx.Row, p *Proposal, extra ...interface{}) error {
+	dest := []interface{}{
+		&p.ProposalID, &p.Proposer, &p.Title, &p.CreateTime, &p.State, &p.WarmUpDuration, &p.ActiveDuration,
+		&p.QueueDuration, &p.GracePeriodDuration, &p.AcceptanceThreshold, &p.MinQuorum, &p.ForVotes, &p.AgainstVotes,
+	}
+
+	return row.Scan(append(dest, extra...)...)
+}
+
+func (a *API) GovernanceProposals(c *gin.Context) {
+	page, limit, offset, err := getPagination(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	rows, err := a.db.Query(c, `
+		select `+proposalColumns+`
+		from governance.proposals
+		order by proposal_id desc
+		limit $1 offset $2
+	`, limit, offset)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query proposals"))
+		return
+	}
+	defer rows.Close()
+
+	proposals := make([]Proposal, 0)
+	for rows.Next() {
+		var p Proposal
+
+		err := scanProposal(rows, &p)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan proposal"))
+			return
+		}
+
+		proposals = append(proposals, p)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read proposals"))
+		return
+	}
+
+	var count int64
+	err = a.db.QueryRow(c, `select count(*) from governance.proposals`).Scan(&count)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not count proposals"))
+		return
+	}
+
+	OK(c, proposals, paginationMeta(page, limit, count))
+}
+
+func (a *API) GovernanceProposal(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("proposalID"), 10, 64)
+	if err != nil {
+		BadRequest(c, errors.New("invalid proposal id"))
+		return
+	}
+
+	var p Proposal
+	err = scanProposal(a.db.QueryRow(c, `
+		select `+proposalColumns+`, description, targets, values, signatures, calldatas
+		from governance.proposals
+		where proposal_id = $1
+	`, id), &p, &p.Description, &p.Targets, &p.Values, &p.Signatures, &p.Calldatas)
+	if err == pgx.ErrNoRows {
+		NotFound(c)
+		return
+	}
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not get proposal"))
+		return
+	}
+
+	OK(c, p)
+}
+
+func (a *API) GovernanceProposalVotes(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("proposalID"), 10, 64)
+	if err != nil {
+		BadRequest(c, errors.New("invalid proposal id"))
+		return
+	}
+
+	page, limit, offset, err := getPagination(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	// the proposal id is always the first argument; filters are numbered after it
+	f := filters{args: []interface{}{id}}
+	if support := c.Query("support"); support != "" {
+		s, err := strconv.ParseBool(support)
+		if err != nil {
+			BadRequest(c, errors.New("invalid support"))
+			return
+		}
+
+		f.add("support = $%d", s)
+	}
+
+	pagination, args := f.paginate(limit, offset)
+
+	rows, err := a.db.Query(c, fmt.Sprintf(`
+		select user_id, support, block_timestamp, power
+		from governance.proposal_votes($1)
+		%s
+		order by power desc
+		%s
+	`, f.where(), pagination), args...)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query proposal votes"))
+		return
+	}
+	defer rows.Close()
+
+	votes := make([]Vote, 0)
+	for rows.Next() {
+		var v Vote
+
+		err := rows.Scan(&v.UserID, &v.Support, &v.BlockTimestamp, &v.Power)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan proposal vote"))
+			return
+		}
+
+		votes = append(votes, v)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read proposal votes"))
+		return
+	}
+
+	var count int64
+	err = a.db.QueryRow(c, fmt.Sprintf(`select count(*) from governance.proposal_votes($1) %s`, f.where()), f.args...).Scan(&count)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not count proposal votes"))
+		return
+	}
+
+	OK(c, votes, paginationMeta(page, limit, count))
+}
+
+func (a *API) GovernanceVoters(c *gin.Context) {
+	page, limit, offset, err := getPagination(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	rows, err := a.db.Query(c, `
+		select user_address,
+			   coalesce(bond_staked, 0),
+			   locked_until,
+			   coalesce(delegated_power, 0),
+			   votes,
+			   proposals,
+			   coalesce(voting_power, 0),
+			   has_active_delegation
+		from governance.voters
+		order by voting_power desc nulls last
+		limit $1 offset $2
+	`, limit, offset)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query voters"))
+		return
+	}
+	defer rows.Close()
+
+	voters := make([]Voter, 0)
+	for rows.Next() {
+		var v Voter
+
+		err := rows.Scan(&v.Address, &v.BondStaked, &v.LockedUntil, &v.DelegatedPower, &v.Votes, &v.Proposals,
+			&v.VotingPower, &v.HasActiveDelegation)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan voter"))
+			return
+		}
+
+		voters = append(voters, v)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read voters"))
+		return
+	}
+
+	var count int64
+	err = a.db.QueryRow(c, `select count(*) from governance.voters`).Scan(&count)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not count voters"))
+		return
+	}
+
+	OK(c, voters, paginationMeta(page, limit, count))
+}
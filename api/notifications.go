@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/types"
+)
+
+type Notification struct {
+	ID              int64            `json:"id"`
+	Target          *string          `json:"target"`
+	Type            string           `json:"notificationType"`
+	StartsOn        *int64           `json:"startsOn"`
+	ExpiresOn       int64            `json:"expiresOn"`
+	Message         *string          `json:"message"`
+	Metadata        types.JSONObject `json:"metadata"`
+	IncludedInBlock *int64           `json:"includedInBlock"`
+}
+
+// Notifications returns the active notifications for the given target (plus the ones broadcast to everyone)
+// that started after the `timestamp` query param
+func (a *API) Notifications(c *gin.Context) {
+	page, limit, offset, err := getPagination(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	target, err := getAddressQuery(c, "target")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	f := filters{conditions: []string{"expires_on > extract(epoch from now())::bigint"}}
+	if target != "" {
+		f.add("(target = $%d or target is null)", target)
+	} else {
+		f.conditions = append(f.conditions, "target is null")
+	}
+
+	if c.Query("timestamp") != "" {
+		ts, err := getTimestamp(c)
+		if err != nil {
+			BadRequest(c, err)
+			return
+		}
+
+		f.add("starts_on > $%d", ts)
+	}
+
+	pagination, args := f.paginate(limit, offset)
+
+	rows, err := a.db.Query(c, fmt.Sprintf(`
+		select id, target, type, starts_on, expires_on, message, coalesce(metadata, '{}'::jsonb), included_in_block
+		from public.notifications
+		%s
+		order by starts_on desc, id desc
+		%s
+	`, f.where(), pagination), args...)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query notifications"))
+		return
+	}
+	defer rows.Close()
+
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		var n Notification
+
+		err := rows.Scan(&n.ID, &n.Target, &n.Type, &n.StartsOn, &n.ExpiresOn, &n.Message, &n.Metadata, &n.IncludedInBlock)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan notification"))
+			return
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read notifications"))
+		return
+	}
+
+	OK(c, notifications, gin.H{"page": page, "limit": limit})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/utils"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+// getPagination reads the `page` and `limit` query params and returns the page, limit and the sql offset
+func getPagination(c *gin.Context) (int64, int64, int64, error) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		return 0, 0, 0, errors.New("invalid page")
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)), 10, 64)
+	if err != nil || limit < 1 {
+		return 0, 0, 0, errors.New("invalid limit")
+	}
+
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	return page, limit, (page - 1) * limit, nil
+}
+
+// getTimestamp reads the `timestamp` query param and falls back to the current time if it is missing
+func getTimestamp(c *gin.Context) (int64, error) {
+	ts := c.Query("timestamp")
+	if ts == "" {
+		return time.Now().Unix(), nil
+	}
+
+	t, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid timestamp")
+	}
+
+	return t, nil
+}
+
+// getAddressParam reads an address from the url path and normalizes it
+func getAddressParam(c *gin.Context, name string) (string, error) {
+	addr, err := utils.ValidateAccount(c.Param(name))
+	if err != nil {
+		return "", errors.Errorf("invalid %s", name)
+	}
+
+	return utils.NormalizeAddress(addr), nil
+}
+
+// getAddressQuery reads an optional address from the query params and normalizes it
+func getAddressQuery(c *gin.Context, name string) (string, error) {
+	value := c.Query(name)
+	if value == "" {
+		return "", nil
+	}
+
+	addr, err := utils.ValidateAccount(value)
+	if err != nil {
+		return "", errors.Errorf("invalid %s", name)
+	}
+
+	return utils.NormalizeAddress(addr), nil
+}
+
+func paginationMeta(page, limit, count int64) gin.H {
+	return gin.H{
+		"page":  page,
+		"limit": limit,
+		"count": count,
+	}
+}
+
+// filters accumulates optional where conditions together with their positional arguments
+type filters struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add appends a condition; `condition` must contain a single `%d` verb that is replaced by the argument position
+func (f *filters) add(condition string, value interface{}) {
+	f.args = append(f.args, value)
+	f.conditions = append(f.conditions, fmt.Sprintf(condition, len(f.args)))
+}
+
+func (f *filters) where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+
+	return "where " + strings.Join(f.conditions, " and ")
+}
+
+// paginate appends limit/offset placeholders to the arguments and returns the matching sql fragment
+func (f *filters) paginate(limit, offset int64) (string, []interface{}) {
+	args := append(append([]interface{}{}, f.args...), limit, offset)
+
+	return fmt.Sprintf("limit $%d offset $%d", len(args)-1, len(args)), args
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func OK(c *gin.Context, data interface{}, meta ...interface{}) {
+	resp := gin.H{
+		"status": http.StatusOK,
+		"data":   data,
+	}
+
+	if len(meta) > 0 {
+		resp["meta"] = meta[0]
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func BadRequest(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status": http.StatusBadRequest,
+		"data":   err.Error(),
+	})
+}
+
+func NotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"status": http.StatusNotFound,
+		"data":   "not found",
+	})
+}
+
+func (a *API) Error(c *gin.Context, err error) {
+	a.logger.Error(err)
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"status": http.StatusInternalServerError,
+		"data":   "internal server error",
+	})
+}
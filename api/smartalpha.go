@@ -0,0 +1,283 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type SmartAlphaPool struct {
+	PoolName           string `json:"poolName"`
+	PoolAddress        string `json:"poolAddress"`
+	PoolTokenAddress   string `json:"poolTokenAddress"`
+	PoolTokenSymbol    string `json:"poolTokenSymbol"`
+	PoolTokenDecimals  int64  `json:"poolTokenDecimals"`
+	JuniorTokenAddress string `json:"juniorTokenAddress"`
+	JuniorTokenSymbol  string `json:"juniorTokenSymbol"`
+	SeniorTokenAddress string `json:"seniorTokenAddress"`
+	SeniorTokenSymbol  string `json:"seniorTokenSymbol"`
+	OracleAddress      string `json:"oracleAddress"`
+	OracleAssetSymbol  string `json:"oracleAssetSymbol"`
+	Epoch1Start        int64  `json:"epoch1Start"`
+	EpochDuration      int64  `json:"epochDuration"`
+	CurrentEpoch       *int64 `json:"currentEpoch"`
+}
+
+type SmartAlphaEpoch struct {
+	EpochID                int64           `json:"epochId"`
+	SeniorLiquidity        decimal.Decimal `json:"seniorLiquidity"`
+	JuniorLiquidity        decimal.Decimal `json:"juniorLiquidity"`
+	UpsideExposureRate     float64         `json:"upsideExposureRate"`
+	DownsideProtectionRate float64         `json:"downsideProtectionRate"`
+	JuniorTokenPriceStart  decimal.Decimal `json:"juniorTokenPriceStart"`
+	SeniorTokenPriceStart  decimal.Decimal `json:"seniorTokenPriceStart"`
+	EpochEntryPrice        decimal.Decimal `json:"epochEntryPrice"`
+	BlockTimestamp         int64           `json:"blockTimestamp"`
+	IncludedInBlock        int64           `json:"includedInBlock"`
+}
+
+type SmartAlphaTransaction struct {
+	PoolAddress     string          `json:"poolAddress"`
+	Tranche         string          `json:"tranche"`
+	TransactionType string          `json:"transactionType"`
+	UserAddress     string          `json:"userAddress"`
+	Amount          decimal.Decimal `json:"amount"`
+	BlockTimestamp  int64           `json:"blockTimestamp"`
+	IncludedInBlock int64           `json:"includedInBlock"`
+	TxHash          string          `json:"txHash"`
+	TxIndex         int64           `json:"txIndex"`
+	LogIndex        int64           `json:"logIndex"`
+}
+
+func (a *API) SmartAlphaPools(c *gin.Context) {
+	rows, err := a.db.Query(c, `
+		select pool_name,
+			   pool_address,
+			   pool_token_address,
+			   pool_token_symbol,
+			   pool_token_decimals,
+			   junior_token_address,
+			   coalesce(junior_token_symbol, ''),
+			   senior_token_address,
+			   coalesce(senior_token_symbol, ''),
+			   oracle_address,
+			   oracle_asset_symbol,
+			   epoch1_start,
+			   epoch_duration,
+			   smart_alpha.pool_active_epoch_at_ts(pool_address, extract(epoch from now())::bigint)
+		from smart_alpha.pools
+		order by pool_name
+	`)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query smart alpha pools"))
+		return
+	}
+	defer rows.Close()
+
+	pools := make([]SmartAlphaPool, 0)
+	for rows.Next() {
+		var p SmartAlphaPool
+
+		err := rows.Scan(&p.PoolName, &p.PoolAddress, &p.PoolTokenAddress, &p.PoolTokenSymbol, &p.PoolTokenDecimals,
+			&p.JuniorTokenAddress, &p.JuniorTokenSymbol, &p.SeniorTokenAddress, &p.SeniorTokenSymbol,
+			&p.OracleAddress, &p.OracleAssetSymbol, &p.Epoch1Start, &p.EpochDuration, &p.CurrentEpoch)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan smart alpha pool"))
+			return
+		}
+
+		pools = append(pools, p)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read smart alpha pools"))
+		return
+	}
+
+	OK(c, pools)
+}
+
+func (a *API) SmartAlphaPoolEpochs(c *gin.Context) {
+	pool, err := getAddressParam(c, "address")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	page, limit, offset, err := getPagination(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	rows, err := a.db.Query(c, `
+		select epoch_id,
+			   senior_liquidity,
+			   junior_liquidity,
+			   upside_exposure_rate,
+			   downside_protection_rate,
+			   junior_token_price_start,
+			   senior_token_price_start,
+			   coalesce(epoch_entry_price, 0),
+			   block_timestamp,
+			   included_in_block
+		from smart_alpha.pool_epoch_info
+		where pool_address = $1
+		order by epoch_id desc
+		limit $2 offset $3
+	`, pool, limit, offset)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query smart alpha epochs"))
+		return
+	}
+	defer rows.Close()
+
+	epochs := make([]SmartAlphaEpoch, 0)
+	for rows.Next() {
+		var e SmartAlphaEpoch
+
+		err := rows.Scan(&e.EpochID, &e.SeniorLiquidity, &e.JuniorLiquidity, &e.UpsideExposureRate, &e.DownsideProtectionRate,
+			&e.JuniorTokenPriceStart, &e.SeniorTokenPriceStart, &e.EpochEntryPrice, &e.BlockTimestamp, &e.IncludedInBlock)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan smart alpha epoch"))
+			return
+		}
+
+		epochs = append(epochs, e)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read smart alpha epochs"))
+		return
+	}
+
+	var count int64
+	err = a.db.QueryRow(c, `select count(*) from smart_alpha.pool_epoch_info where pool_address = $1`, pool).Scan(&count)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not count smart alpha epochs"))
+		return
+	}
+
+	OK(c, epochs, paginationMeta(page, limit, count))
+}
+
+func (a *API) SmartAlphaTransactions(c *gin.Context) {
+	page, limit, offset, err := getPagination(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var f filters
+
+	pool, err := getAddressQuery(c, "poolAddress")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+	if pool != "" {
+		f.add("pool_address = $%d", pool)
+	}
+
+	user, err := getAddressQuery(c, "userAddress")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+	if user != "" {
+		f.add("user_address = $%d", user)
+	}
+
+	if t := c.Query("transactionType"); t != "" {
+		f.add("transaction_type::text = $%d", strings.ToUpper(t))
+	}
+
+	pagination, args := f.paginate(limit, offset)
+
+	rows, err := a.db.Query(c, fmt.Sprintf(`
+		select pool_address,
+			   tranche,
+			   transaction_type::text,
+			   user_address,
+			   amount,
+			   block_timestamp,
+			   included_in_block,
+			   tx_hash,
+			   tx_index,
+			   log_index
+		from smart_alpha.transaction_history
+		%s
+		order by included_in_block desc, tx_index desc, log_index desc
+		%s
+	`, f.where(), pagination), args...)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query smart alpha transactions"))
+		return
+	}
+	defer rows.Close()
+
+	txs := make([]SmartAlphaTransaction, 0)
+	for rows.Next() {
+		var t SmartAlphaTransaction
+
+		err := rows.Scan(&t.PoolAddress, &t.Tranche, &t.TransactionType, &t.UserAddress, &t.Amount,
+			&t.BlockTimestamp, &t.IncludedInBlock, &t.TxHash, &t.TxIndex, &t.LogIndex)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan smart alpha transaction"))
+			return
+		}
+
+		txs = append(txs, t)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read smart alpha transactions"))
+		return
+	}
+
+	var count int64
+	err = a.db.QueryRow(c, fmt.Sprintf(`select count(*) from smart_alpha.transaction_history %s`, f.where()), f.args...).Scan(&count)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not count smart alpha transactions"))
+		return
+	}
+
+	OK(c, txs, paginationMeta(page, limit, count))
+}
+
+func (a *API) SmartAlphaUserPortfolioValue(c *gin.Context) {
+	user, err := getAddressParam(c, "address")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	ts, err := getTimestamp(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var junior, senior, entryQueue, exitQueue *decimal.Decimal
+	err = a.db.QueryRow(c, `
+		select smart_alpha.junior_portfolio_value_at_ts($1, $2),
+			   smart_alpha.senior_portfolio_value_at_ts($1, $2),
+			   smart_alpha.entry_queue_portfolio_value_at_ts($1, $2),
+			   smart_alpha.exit_queue_portfolio_value_at_ts($1, $2)
+	`, user, ts).Scan(&junior, &senior, &entryQueue, &exitQueue)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not get smart alpha portfolio value"))
+		return
+	}
+
+	OK(c, gin.H{
+		"timestamp":       ts,
+		"juniorValue":     junior,
+		"seniorValue":     senior,
+		"entryQueueValue": entryQueue,
+		"exitQueueValue":  exitQueue,
+	})
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type SmartYieldPool struct {
+	ProtocolID          string `json:"protocolId"`
+	PoolAddress         string `json:"poolAddress"`
+	ControllerAddress   string `json:"controllerAddress"`
+	ModelAddress        string `json:"modelAddress"`
+	ProviderAddress     string `json:"providerAddress"`
+	OracleAddress       string `json:"oracleAddress"`
+	JuniorBondAddress   string `json:"juniorBondAddress"`
+	SeniorBondAddress   string `json:"seniorBondAddress"`
+	ReceiptTokenAddress string `json:"receiptTokenAddress"`
+	UnderlyingAddress   string `json:"underlyingAddress"`
+	UnderlyingSymbol    string `json:"underlyingSymbol"`
+	UnderlyingDecimals  int64  `json:"underlyingDecimals"`
+}
+
+type SmartYieldTransaction struct {
+	ProtocolID             string          `json:"protocolId"`
+	PoolAddress            string          `json:"poolAddress"`
+	UnderlyingTokenAddress string          `json:"underlyingTokenAddress"`
+	UserAddress            string          `json:"userAddress"`
+	Amount                 decimal.Decimal `json:"amount"`
+	Tranche                string          `json:"tranche"`
+	TransactionType        string          `json:"transactionType"`
+	BlockTimestamp         int64           `json:"blockTimestamp"`
+	IncludedInBlock        int64           `json:"includedInBlock"`
+	TxHash                 string          `json:"txHash"`
+	TxIndex                int64           `json:"txIndex"`
+	LogIndex               int64           `json:"logIndex"`
+}
+
+func (a *API) SmartYieldPools(c *gin.Context) {
+	rows, err := a.db.Query(c, `
+		select protocol_id,
+			   pool_address,
+			   controller_address,
+			   model_address,
+			   provider_address,
+			   oracle_address,
+			   junior_bond_address,
+			   senior_bond_address,
+			   receipt_token_address,
+			   underlying_address,
+			   underlying_symbol,
+			   underlying_decimals
+		from smart_yield.pools
+		order by protocol_id, underlying_symbol
+	`)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query smart yield pools"))
+		return
+	}
+	defer rows.Close()
+
+	pools := make([]SmartYieldPool, 0)
+	for rows.Next() {
+		var p SmartYieldPool
+
+		err := rows.Scan(&p.ProtocolID, &p.PoolAddress, &p.ControllerAddress, &p.ModelAddress, &p.ProviderAddress,
+			&p.OracleAddress, &p.JuniorBondAddress, &p.SeniorBondAddress, &p.ReceiptTokenAddress,
+			&p.UnderlyingAddress, &p.UnderlyingSymbol, &p.UnderlyingDecimals)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan smart yield pool"))
+			return
+		}
+
+		pools = append(pools, p)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read smart yield pools"))
+		return
+	}
+
+	OK(c, pools)
+}
+
+func (a *API) SmartYieldTransactions(c *gin.Context) {
+	page, limit, offset, err := getPagination(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var f filters
+
+	pool, err := getAddressQuery(c, "poolAddress")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+	if pool != "" {
+		f.add("pool_address = $%d", pool)
+	}
+
+	user, err := getAddressQuery(c, "userAddress")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+	if user != "" {
+		f.add("user_address = $%d", user)
+	}
+
+	if t := c.Query("transactionType"); t != "" {
+		f.add("transaction_type::text = $%d", strings.ToUpper(t))
+	}
+
+	pagination, args := f.paginate(limit, offset)
+
+	rows, err := a.db.Query(c, fmt.Sprintf(`
+		select protocol_id,
+			   pool_address,
+			   underlying_token_address,
+			   user_address,
+			   coalesce(amount, 0),
+			   tranche,
+			   transaction_type::text,
+			   block_timestamp,
+			   included_in_block,
+			   tx_hash,
+			   tx_index,
+			   log_index
+		from smart_yield.transaction_history
+		%s
+		order by included_in_block desc, tx_index desc, log_index desc
+		%s
+	`, f.where(), pagination), args...)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not query smart yield transactions"))
+		return
+	}
+	defer rows.Close()
+
+	txs := make([]SmartYieldTransaction, 0)
+	for rows.Next() {
+		var t SmartYieldTransaction
+
+		err := rows.Scan(&t.ProtocolID, &t.PoolAddress, &t.UnderlyingTokenAddress, &t.UserAddress, &t.Amount, &t.Tranche,
+			&t.TransactionType, &t.BlockTimestamp, &t.IncludedInBlock, &t.TxHash, &t.TxIndex, &t.LogIndex)
+		if err != nil {
+			a.Error(c, errors.Wrap(err, "could not scan smart yield transaction"))
+			return
+		}
+
+		txs = append(txs, t)
+	}
+
+	if rows.Err() != nil {
+		a.Error(c, errors.Wrap(rows.Err(), "could not read smart yield transactions"))
+		return
+	}
+
+	var count int64
+	err = a.db.QueryRow(c, fmt.Sprintf(`select count(*) from smart_yield.transaction_history %s`, f.where()), f.args...).Scan(&count)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not count smart yield transactions"))
+		return
+	}
+
+	OK(c, txs, paginationMeta(page, limit, count))
+}
+
+func (a *API) SmartYieldUserPortfolioValue(c *gin.Context) {
+	user, err := getAddressParam(c, "address")
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	ts, err := getTimestamp(c)
+	if err != nil {
+		BadRequest(c, err)
+		return
+	}
+
+	var junior, senior *decimal.Decimal
+	err = a.db.QueryRow(c, `
+		select smart_yield.junior_portfolio_value_at_ts($1, $2),
+			   smart_yield.senior_portfolio_value_at_ts($1, $2)
+	`, user, ts).Scan(&junior, &senior)
+	if err != nil {
+		a.Error(c, errors.Wrap(err, "could not get smart yield portfolio value"))
+		return
+	}
+
+	OK(c, gin.H{
+		"timestamp":   ts,
+		"juniorValue": junior,
+		"seniorValue": senior,
+	})
+}
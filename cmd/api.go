@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/barnbridge/meminero/api"
+	"github.com/barnbridge/meminero/db"
+)
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "Serve the indexed data through a read-only REST API",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		d, err := db.New()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		a := api.New(d.Connection())
+
+		err = a.Run(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		d.Connection().Close()
+
+		log.Info("Work done. Goodbye!")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(apiCmd)
+
+	addDBFlags(apiCmd)
+	addAPIFlags(apiCmd)
+}
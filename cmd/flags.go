@@ -24,17 +24,54 @@ func addRedisFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().String("redis.password", "", "Redis password")
 }
 
+func addQueueFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("queue.backend", "redis", "Where the task queue and the block locks are kept: redis or postgres")
+	cmd.PersistentFlags().Int64("queue.weights.repair", 10, "Weight of the lane of the blocks queued by the integrity checker and the requeued dead letters")
+	cmd.PersistentFlags().Int64("queue.weights.backfill", 1, "Weight of the lane of the blocks queued manually")
+}
+
+func addRawStoreFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("raw-store.enabled", false, "Keep the raw data of the scraped blocks so they can be processed again without the node (see the reprocess command)")
+	cmd.PersistentFlags().String("raw-store.backend", "disk", "Where to keep the raw block data: disk or postgres")
+	cmd.PersistentFlags().String("raw-store.path", "./raw-blocks", "Folder of the raw block data (disk backend)")
+}
+
+func addLeaderFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("leader-election.enabled", true, "Only run the singleton background loops (queue keeper, integrity checker, notifications worker) on the elected leader")
+	cmd.PersistentFlags().String("leader-election.backend", "postgres", "Where the leadership is held: postgres (advisory lock) or redis (lease)")
+	cmd.PersistentFlags().Duration("leader-election.ttl", 15*time.Second, "How long the leadership survives a leader that stopped renewing it")
+}
+
 func addMetricsFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().Int64("metrics.port", 9909, "Port on which to serve Prometheus metrics")
 }
 
+func addAPIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("api.port", "3001", "Port on which to serve the REST API")
+	cmd.PersistentFlags().Bool("api.dev-cors", false, "Enable development cors for the REST API")
+	cmd.PersistentFlags().String("api.dev-cors-host", "", "Allowed host for development cors")
+}
+
 func addFeatureFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().Bool("feature.integrity.enabled", true, "Enable/disable the integrity checker")
+	cmd.PersistentFlags().Bool("feature.integrity.data.enabled", false, "Compare the logs of the tracked contracts with the stored events before moving the integrity checkpoint")
+	cmd.PersistentFlags().Int64("feature.integrity.data.max-blocks", 10000, "Maximum number of blocks checked against the chain logs in a single integrity run")
+	cmd.PersistentFlags().Int64("feature.integrity.data.chunk-size", 1000, "Number of blocks covered by a single eth_getLogs call of the data checks")
 	cmd.PersistentFlags().Bool("feature.queuekeeper.enabled", true, "Enable/disable the queue keeper (watch new heads and store into the queue)")
 	cmd.PersistentFlags().Int64("feature.queuekeeper.lag", 10, "The amount of blocks to lag behind the tip of the chain")
 	cmd.PersistentFlags().Bool("feature.replace-blocks", false, "Enable this if the scraper should replace existing blocks instead of skipping them")
+	cmd.PersistentFlags().String("feature.finality.mode", "lag", "Which blocks are considered final: lag (feature.queuekeeper.lag blocks behind the best block), safe or finalized (block tags of the node)")
+	cmd.PersistentFlags().Bool("feature.finality.dual", false, "Process the head blocks right away and mark them as unconfirmed until they are final")
+	cmd.PersistentFlags().Int64("feature.reorg.max-depth", 100, "Maximum number of stored blocks rolled back while walking back to the common ancestor of a reorg")
 	cmd.PersistentFlags().Bool("feature.contract-state.enabled", true, "Enable/disable state scraping (if enabled, it requires archive node support)")
 	cmd.PersistentFlags().Bool("feature.requeue-failed-blocks", true, "Enable this if the scraper should retry failed blocks instead of skipping them. If false, disable integrity checker.")
+	cmd.PersistentFlags().Int64("feature.retry.max-attempts", 10, "Number of attempts after which a failing block is moved to the dead-letter queue (0 to retry forever)")
+	cmd.PersistentFlags().Duration("feature.retry.backoff", 5*time.Second, "Delay before retrying a failed block; it doubles with every failed attempt")
+	cmd.PersistentFlags().Duration("feature.retry.max-backoff", time.Hour, "Maximum delay before retrying a failed block")
+	cmd.PersistentFlags().Bool("feature.log-scraping", false, "Enable this to scrape only the block headers and the logs of the monitored contracts (eth_getLogs) instead of full blocks with receipts")
+	cmd.PersistentFlags().Bool("feature.batch.enabled", false, "Enable/disable saving windows of consecutive blocks in a single database transaction while catching up")
+	cmd.PersistentFlags().Int64("feature.batch.threshold", 100, "How many blocks behind the best block the scraper must be before it starts batching")
+	cmd.PersistentFlags().Int64("feature.batch.size", 20, "Maximum number of consecutive blocks saved in a single database transaction")
 }
 
 func addETHFlags(cmd *cobra.Command) {
@@ -42,6 +79,13 @@ func addETHFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().String("eth.client.ws", "", "WS endpoint of JSON-RPC enabled Ethereum node (provide this only if you want to use websocket subscription for tracking best block)")
 	cmd.PersistentFlags().Duration("eth.client.poll-interval", 15*time.Second, "Interval to be used for polling the Ethereum node for best block")
 	cmd.PersistentFlags().Int("eth.max-batch", 100, "Maximum JSON-RPC requests to batch together")
+	cmd.PersistentFlags().Duration("eth.timeout", 5*time.Second, "HTTP timeout of the JSON-RPC requests")
+	cmd.PersistentFlags().Duration("eth.health-check.interval", 15*time.Second, "Interval at which the JSON-RPC endpoints are checked")
+	cmd.PersistentFlags().Int64("eth.health-check.max-lag", 5, "Number of blocks an endpoint can be behind the best one before it is considered unhealthy")
+	cmd.PersistentFlags().String("eth.multicall.address", "", "Address of the Multicall2 contract used to aggregate contract calls (leave empty to disable aggregation)")
+	cmd.PersistentFlags().Int64("eth.multicall.start-at-block", 0, "Block at which the Multicall2 contract was deployed; calls at older blocks are not aggregated")
+	cmd.PersistentFlags().Int("eth.multicall.max-calls", 100, "Maximum number of calls aggregated in a single eth_call")
+	cmd.PersistentFlags().Duration("eth.multicall.wait", 10*time.Millisecond, "How long to wait for other calls at the same block before sending an aggregated call")
 }
 
 func addGenerateETHTypesFlags(cmd *cobra.Command) {
@@ -79,6 +123,8 @@ func addStorableSmartExposureFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().String("storable.smartExposure.EPoolPeripheryAddress", "", "Address of EPool periphery contract")
 	cmd.PersistentFlags().String("storable.smartExposure.ETokenFactoryAddress", "", "Address of EToken factory contract")
 	cmd.PersistentFlags().String("storable.smartExposure.EPoolHelperAddress", "", "Address of EPool helper contract")
+	cmd.PersistentFlags().String("storable.smartExposure.sampling.mode", "every-block", "When to take contract state snapshots: every-block, blocks, seconds or events")
+	cmd.PersistentFlags().Int64("storable.smartExposure.sampling.interval", 0, "Number of blocks (blocks mode) or seconds of block time (seconds mode) between contract state snapshots")
 }
 
 func addStorableSmartYieldFlags(cmd *cobra.Command) {
@@ -87,14 +133,25 @@ func addStorableSmartYieldFlags(cmd *cobra.Command) {
 
 	// using string instead of string slice because we can't pass string slice through env
 	cmd.PersistentFlags().String("storable.smartYield.rewards.factories", "", "Addresses of Pool Factories separated by comma")
+	cmd.PersistentFlags().String("storable.smartYield.sampling.mode", "every-block", "When to take contract state snapshots: every-block, blocks, seconds or events")
+	cmd.PersistentFlags().Int64("storable.smartYield.sampling.interval", 0, "Number of blocks (blocks mode) or seconds of block time (seconds mode) between contract state snapshots")
 }
 
 func addStorableSmartAlphaFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().Bool("storable.smartAlpha.enabled", true, "Enable/disable Smart Alpha scraping")
+	cmd.PersistentFlags().String("storable.smartAlpha.sampling.mode", "every-block", "When to take contract state snapshots: every-block, blocks, seconds or events")
+	cmd.PersistentFlags().Int64("storable.smartAlpha.sampling.interval", 0, "Number of blocks (blocks mode) or seconds of block time (seconds mode) between contract state snapshots")
 }
 
 func addStorableTokenPricesFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().Bool("storable.tokenPrices.enabled", true, "Enable/disable token prices storable")
+	cmd.PersistentFlags().String("storable.tokenPrices.sampling.mode", "every-block", "When to take contract state snapshots: every-block, blocks, seconds or events")
+	cmd.PersistentFlags().Int64("storable.tokenPrices.sampling.interval", 0, "Number of blocks (blocks mode) or seconds of block time (seconds mode) between contract state snapshots")
+}
+
+func addStorableContractEventsFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("storable.contractEvents.enabled", false, "Enable/disable indexing the events of the contracts listed in storable.contractEvents.contracts")
+	cmd.PersistentFlags().Bool("storable.contractEvents.typed-tables", false, "Also store the events in a typed table per event in the contract_events schema (created automatically)")
 }
 
 func addSyncerFlags(cmd *cobra.Command) {
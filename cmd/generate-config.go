@@ -52,7 +52,11 @@ func init() {
 
 	addDBFlags(generateConfigCmd)
 	addRedisFlags(generateConfigCmd)
+	addQueueFlags(generateConfigCmd)
+	addRawStoreFlags(generateConfigCmd)
+	addLeaderFlags(generateConfigCmd)
 	addMetricsFlags(generateConfigCmd)
+	addAPIFlags(generateConfigCmd)
 	addFeatureFlags(generateConfigCmd)
 	addETHFlags(generateConfigCmd)
 	addGenerateETHTypesFlags(generateConfigCmd)
@@ -66,6 +70,7 @@ func init() {
 	addStorableSmartExposureFlags(generateConfigCmd)
 	addStorableSmartAlphaFlags(generateConfigCmd)
 	addStorableTokenPricesFlags(generateConfigCmd)
+	addStorableContractEventsFlags(generateConfigCmd)
 
 	addSyncerFlags(generateConfigCmd)
 }
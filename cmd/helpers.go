@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +11,10 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/lacasian/ethwheels/bestblock"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/db"
+	"github.com/barnbridge/meminero/state"
 )
 
 func initLogging() {
@@ -84,3 +89,23 @@ func initBestBlockTracker(config bestblock.Config) (*bestblock.Tracker, error) {
 
 	return bbtracker, nil
 }
+
+// initQueueState returns a state manager for the commands that only work with the task queue; the database is only
+// needed (and migrated) when the queue is kept in postgres
+func initQueueState() (*state.Manager, error) {
+	if config.Store.Queue.Backend != state.QueueBackendPostgres {
+		return state.NewManager(nil)
+	}
+
+	d, err := db.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to database")
+	}
+
+	err = d.Migrate(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not migrate database")
+	}
+
+	return state.NewManager(d.Connection())
+}
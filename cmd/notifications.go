@@ -9,6 +9,7 @@ import (
 
 	"github.com/barnbridge/meminero/db"
 	"github.com/barnbridge/meminero/notifications"
+	"github.com/barnbridge/meminero/state/leader"
 )
 
 var notificationsCmd = &cobra.Command{
@@ -28,10 +29,13 @@ var notificationsCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
-		n.Run(ctx)
+		// only the elected leader processes the jobs, so the replicas don't send the same notifications
+		elector, err := leader.New(d.Connection())
+		if err != nil {
+			log.Fatal(err)
+		}
 
-		// TODO i think listening to ctx done like this does not leave time for threads to exit cleanly
-		<-ctx.Done()
+		elector.Run(ctx, "notifications", n.Run)
 
 		log.Info("Work done. Goodbye!")
 	},
@@ -41,4 +45,6 @@ func init() {
 	RootCmd.AddCommand(notificationsCmd)
 
 	addDBFlags(notificationsCmd)
+	addRedisFlags(notificationsCmd)
+	addLeaderFlags(notificationsCmd)
 }
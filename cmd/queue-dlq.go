@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var queueDLQCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Manage the blocks that were moved to the dead-letter queue after failing too many times",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.HelpFunc()(cmd, args)
+	},
+}
+
+var queueDLQListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the dead-lettered blocks",
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer s.Close()
+
+		failures, err := s.DeadLetters()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(failures) == 0 {
+			fmt.Println("The dead-letter queue is empty.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "BLOCK\tATTEMPTS\tFAILED AT\tLAST ERROR")
+		for _, f := range failures {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", f.Block, f.Attempts, f.FailedAt.Format(time.RFC3339), truncate(f.LastError, 80))
+		}
+		w.Flush()
+	},
+}
+
+var queueDLQInspectCmd = &cobra.Command{
+	Use:   "inspect <block>",
+	Short: "Show the failure record of a block, including the full last error",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		block, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			log.Fatal("invalid block number")
+		}
+
+		s, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer s.Close()
+
+		f, err := s.TaskFailure(block)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if f == nil {
+			fmt.Printf("Block %d has no recorded failures.\n", block)
+			return
+		}
+
+		fmt.Printf("Block:         %d\n", f.Block)
+		fmt.Printf("Dead-lettered: %t\n", f.Dead)
+		fmt.Printf("Attempts:      %d\n", f.Attempts)
+		fmt.Printf("Failed at:     %s\n", f.FailedAt.Format(time.RFC3339))
+		fmt.Printf("Last error:    %s\n", f.LastError)
+	},
+}
+
+var queueDLQRequeueCmd = &cobra.Command{
+	Use:   "requeue [block...]",
+	Short: "Move dead-lettered blocks back to the queue with a fresh attempts counter",
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer s.Close()
+
+		var blocks []int64
+		if viper.GetBool("all") {
+			failures, err := s.DeadLetters()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, f := range failures {
+				blocks = append(blocks, f.Block)
+			}
+		} else {
+			if len(args) == 0 {
+				log.Fatal("no block specified (use --all to requeue all the dead-lettered blocks)")
+			}
+
+			for _, a := range args {
+				b, err := strconv.ParseInt(a, 10, 64)
+				if err != nil {
+					log.Fatalf("invalid block number: %s", a)
+				}
+
+				blocks = append(blocks, b)
+			}
+		}
+
+		requeued := 0
+		for _, b := range blocks {
+			ok, err := s.RequeueDeadLetter(b)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if !ok {
+				log.Warnf("block %d is not in the dead-letter queue; skipping", b)
+				continue
+			}
+
+			requeued++
+		}
+
+		fmt.Printf("Requeued %d blocks.\n", requeued)
+	},
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	return s[:max-3] + "..."
+}
+
+func init() {
+	queueCmd.AddCommand(queueDLQCmd)
+	queueDLQCmd.AddCommand(queueDLQListCmd, queueDLQInspectCmd, queueDLQRequeueCmd)
+
+	queueDLQRequeueCmd.Flags().Bool("all", false, "Requeue all the dead-lettered blocks")
+}
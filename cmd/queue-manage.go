@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/barnbridge/meminero/state"
+)
+
+var queueStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the depth and the lowest and highest pending blocks of every lane, and estimate the time to drain the queue",
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer s.Close()
+
+		stats, err := s.QueueStats()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		locks, err := s.Locks()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LANE\tDEPTH\tLOWEST\tHIGHEST")
+		for _, l := range stats {
+			if l.Depth == 0 {
+				fmt.Fprintf(w, "%s\t0\t-\t-\n", l.Lane)
+				continue
+			}
+
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", l.Lane, l.Depth, l.Oldest, l.Newest)
+		}
+		w.Flush()
+
+		depth := totalDepth(stats)
+		fmt.Printf("\nPending blocks: %d\n", depth)
+		fmt.Printf("Held locks:     %d\n", len(locks))
+
+		sample := viper.GetDuration("sample")
+		if sample <= 0 || depth == 0 {
+			return
+		}
+
+		// the drain rate is measured on the whole queue, so it already accounts for the blocks added in the meantime
+		// by the queue keeper or the integrity checker
+		fmt.Printf("\nMeasuring the drain rate for %s...\n", sample)
+		time.Sleep(sample)
+
+		stats, err = s.QueueStats()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		drained := depth - totalDepth(stats)
+		if drained <= 0 {
+			fmt.Println("The queue is not draining; it's growing or no worker is running.")
+			return
+		}
+
+		rate := float64(drained) / sample.Seconds()
+		eta := time.Duration(float64(totalDepth(stats))/rate) * time.Second
+
+		fmt.Printf("Drain rate:     %.2f blocks/s\n", rate)
+		fmt.Printf("Time to drain:  %s\n", eta.Round(time.Second))
+	},
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the pending blocks of the queue",
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer s.Close()
+
+		lanes, err := parseLanesFlag()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		from, to := rangeFlags()
+		limit := viper.GetInt64("limit")
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "BLOCK\tLANE")
+		for _, l := range lanes {
+			blocks, err := s.ListTasks(l, from, to, limit)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, b := range blocks {
+				fmt.Fprintf(w, "%d\t%s\n", b, l)
+			}
+		}
+		w.Flush()
+	},
+}
+
+var queueRemoveCmd = &cobra.Command{
+	Use:   "remove <block...>",
+	Short: "Remove blocks from the queue",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer s.Close()
+
+		lanes, err := parseLanesFlag()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var removed int64
+		for _, a := range args {
+			b, err := strconv.ParseInt(a, 10, 64)
+			if err != nil {
+				log.Fatalf("invalid block number: %s", a)
+			}
+
+			for _, l := range lanes {
+				n, err := s.RemoveTaskRange(l, b, b)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				removed += n
+			}
+		}
+
+		fmt.Printf("Removed %d tasks.\n", removed)
+	},
+}
+
+var queueClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove a range of blocks from the queue (all of them if no range is given); the failure records and the dead-letter queue are kept",
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer s.Close()
+
+		lanes, err := parseLanesFlag()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		from, to := rangeFlags()
+
+		var removed int64
+		for _, l := range lanes {
+			n, err := s.RemoveTaskRange(l, from, to)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			log.Infof("removed %d tasks from lane %s", n, l)
+			removed += n
+		}
+
+		fmt.Printf("Removed %d tasks.\n", removed)
+	},
+}
+
+var queueLocksCmd = &cobra.Command{
+	Use:   "locks",
+	Short: "List the block locks that are currently held and when they expire",
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer s.Close()
+
+		locks, err := s.Locks()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(locks) == 0 {
+			fmt.Println("No locks are held.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tTTL")
+		for _, l := range locks {
+			fmt.Fprintf(w, "lock:%s\t%s\n", l.Key, l.TTL.Round(time.Millisecond))
+		}
+		w.Flush()
+	},
+}
+
+func totalDepth(stats []state.LaneStats) int64 {
+	var depth int64
+	for _, l := range stats {
+		depth += l.Depth
+	}
+
+	return depth
+}
+
+// parseLanesFlag returns the lane selected with --lane, or all of them if the flag is empty
+func parseLanesFlag() ([]state.Lane, error) {
+	lane := viper.GetString("lane")
+	if lane == "" {
+		return state.Lanes, nil
+	}
+
+	l, err := state.ParseLane(lane)
+	if err != nil {
+		return nil, err
+	}
+
+	return []state.Lane{l}, nil
+}
+
+// rangeFlags returns the range selected with --from and --to; a missing bound means the range is open on that side
+func rangeFlags() (int64, int64) {
+	from := viper.GetInt64("from")
+	if from < 0 {
+		from = 0
+	}
+
+	to := viper.GetInt64("to")
+	if to < 0 {
+		to = math.MaxInt64
+	}
+
+	if from > to {
+		log.Fatal("--from must not be greater than --to")
+	}
+
+	return from, to
+}
+
+func init() {
+	queueCmd.AddCommand(queueStatusCmd, queueListCmd, queueRemoveCmd, queueClearCmd, queueLocksCmd)
+
+	queueStatusCmd.Flags().Duration("sample", 10*time.Second, "How long to measure the drain rate for to estimate the time to drain the queue (0 to skip the estimate)")
+
+	for _, c := range []*cobra.Command{queueListCmd, queueRemoveCmd, queueClearCmd} {
+		c.Flags().String("lane", "", "Only work on this lane: head, repair or backfill (default: all lanes)")
+	}
+
+	for _, c := range []*cobra.Command{queueListCmd, queueClearCmd} {
+		c.Flags().Int64("from", -1, "Lowest block of the range, inclusive")
+		c.Flags().Int64("to", -1, "Highest block of the range, inclusive")
+	}
+
+	queueListCmd.Flags().Int64("limit", 100, "Maximum number of blocks to list per lane")
+}
@@ -18,14 +18,19 @@ var queueCmd = &cobra.Command{
 	Use:   "queue",
 	Short: "Manually add a block to the todo queue",
 	Run: func(cmd *cobra.Command, args []string) {
-		r, err := state.NewManager(nil)
+		r, err := initQueueState()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		lane, err := state.ParseLane(viper.GetString("lane"))
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		block := viper.GetInt64("block")
 		if block > 0 {
-			err := r.AddTaskToQueue(block)
+			err := r.AddTaskToQueue(lane, block)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -69,7 +74,7 @@ var queueCmd = &cobra.Command{
 				return
 			}
 
-			err = r.AddBatchToQueue(blocks)
+			err = r.AddBatchToQueue(lane, blocks)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -81,7 +86,7 @@ var queueCmd = &cobra.Command{
 		to := viper.GetInt64("to")
 		if from > 0 && to > 0 {
 			for i := from; i <= to; i++ {
-				err := r.AddTaskToQueue(i)
+				err := r.AddTaskToQueue(lane, i)
 				if err != nil {
 					log.Fatal(err)
 				}
@@ -147,6 +152,7 @@ func init() {
 	RootCmd.AddCommand(queueCmd)
 
 	addRedisFlags(queueCmd)
+	addQueueFlags(queueCmd)
 	addDBFlags(queueCmd)
 
 	queueCmd.Flags().Int64("block", -1, "Add a single block in the todo queue")
@@ -154,4 +160,5 @@ func init() {
 	queueCmd.Flags().Int64("to", -1, "Add a series of blocks into the todo queue ending with the provided number, inclusive (only use in combination with --from)")
 	queueCmd.Flags().String("file", "", "Add a series of blocks specified in file, one block per line")
 	queueCmd.Flags().Bool("omit-if-exists", false, "Omit blocks that already exist in the database")
+	queueCmd.Flags().String("lane", string(state.LaneBackfill), "Lane in which to queue the blocks: head, repair or backfill")
 }
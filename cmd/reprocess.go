@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/db"
+	"github.com/barnbridge/meminero/eth"
+	"github.com/barnbridge/meminero/processor"
+	"github.com/barnbridge/meminero/processor/storables/contractevents"
+	"github.com/barnbridge/meminero/rawstore"
+	"github.com/barnbridge/meminero/state"
+)
+
+var reprocessCmd = &cobra.Command{
+	Use:   "reprocess",
+	Short: "Execute some storables again on a range of stored blocks, using the block data from the raw store",
+	Long: `Execute some storables again on a range of stored blocks, using the block data from the raw store
+
+The data of the chosen storables is rolled back and saved again block by block; the other storables are not touched,
+except for the ones whose results the chosen storables consume, which are executed but not saved.
+The block data comes from the raw store instead of the node, but the contract state storables still call the node.
+Only the blocks that are stored in the database and kept in the raw store (with the same hash) are reprocessed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		storables, err := cmd.Flags().GetStringSlice("storable")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(storables) == 0 {
+			log.Fatal("at least one --storable must be specified")
+		}
+
+		from := viper.GetInt64("from")
+		to := viper.GetInt64("to")
+		if from < 0 || to < 0 {
+			log.Fatal("both --from and --to must be specified")
+		}
+
+		if from > to {
+			log.Fatal("--from must be lower than or equal to --to")
+		}
+
+		g, err := processor.StorableGraph()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, id := range storables {
+			if n := node(g, id); !n.Enabled {
+				log.Fatalf("storable %s is unknown or disabled (see the storables graph command)", id)
+			}
+		}
+
+		err = rawstore.CheckConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = eth.Init()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		d, err := db.New()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = d.Migrate(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if config.Store.Storable.ContractEvents.Enabled {
+			err = contractevents.Init(ctx, d.Connection())
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		raw, err := rawstore.New(d.Connection())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		s := state.NewManagerWithoutQueue(d.Connection())
+
+		var reprocessed int64
+		var notStored, notKept []int64
+
+		for b := from; b <= to; b++ {
+			if ctx.Err() != nil {
+				log.Warnf("interrupted; reprocessed blocks up to %d", b-1)
+				break
+			}
+
+			var hash string
+			err = d.Connection().QueryRow(ctx, `select block_hash from blocks where number = $1`, b).Scan(&hash)
+			if err == pgx.ErrNoRows {
+				notStored = append(notStored, b)
+				continue
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			blk, err := raw.Get(ctx, b, hash)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if blk == nil {
+				notKept = append(notKept, b)
+				continue
+			}
+
+			// the same as before scraping a block; the storables rely on the contracts discovered up to now
+			err = s.RefreshCache(ctx)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			p, err := processor.New(blk, s)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			done, err := p.Reprocess(ctx, d.Connection(), storables)
+			if err != nil {
+				log.WithField("block", b).Fatal(err)
+			}
+
+			if !done {
+				// replaced by a scraper after we looked it up
+				notStored = append(notStored, b)
+				continue
+			}
+
+			reprocessed++
+			log.WithField("block", b).Debug("reprocessed block")
+		}
+
+		if len(notStored) > 0 {
+			log.WithField("blocks", notStored).Warnf("skipped %d blocks that are not stored in the database", len(notStored))
+		}
+
+		if len(notKept) > 0 {
+			log.WithField("blocks", notKept).Warnf("skipped %d blocks that are not in the raw store", len(notKept))
+		}
+
+		log.Infof("reprocessed %d blocks", reprocessed)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(reprocessCmd)
+
+	reprocessCmd.Flags().StringSlice("storable", nil, "ID of a storable to execute again (see the storables graph command); can be repeated")
+	// it would shadow the storable config section
+	reprocessCmd.Flags().SetAnnotation("storable", commandLineOnly, []string{"true"})
+	reprocessCmd.Flags().Int64("from", -1, "The first block of the range")
+	reprocessCmd.Flags().Int64("to", -1, "The last block of the range, inclusive")
+
+	addDBFlags(reprocessCmd)
+	addRawStoreFlags(reprocessCmd)
+	addFeatureFlags(reprocessCmd)
+	addETHFlags(reprocessCmd)
+	addGenerateETHTypesFlags(reprocessCmd)
+
+	addStorableAccountERC20TransfersFlags(reprocessCmd)
+	addStorableGovernanceFlags(reprocessCmd)
+	addStorableMonitoredERC20TransfersFlags(reprocessCmd)
+	addStorableBarnFlags(reprocessCmd)
+	addStorableYieldFarmingFlags(reprocessCmd)
+	addStorableSmartYieldFlags(reprocessCmd)
+	addStorableSmartExposureFlags(reprocessCmd)
+	addStorableSmartAlphaFlags(reprocessCmd)
+	addStorableTokenPricesFlags(reprocessCmd)
+	addStorableContractEventsFlags(reprocessCmd)
+}
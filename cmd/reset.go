@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/barnbridge/meminero/config"
 	"github.com/barnbridge/meminero/db"
 	"github.com/barnbridge/meminero/state"
 )
@@ -29,19 +30,22 @@ var resetCmd = &cobra.Command{
 			}
 		}
 
-		stateManager, err := state.NewManager(nil)
-		if err != nil {
-			log.Fatal(err)
-		}
+		// the postgres queue lives in the public schema, so it's dropped together with the rest of the database
+		if config.Store.Queue.Backend != state.QueueBackendPostgres {
+			stateManager, err := state.NewManager(nil)
+			if err != nil {
+				log.Fatal(err)
+			}
 
-		fmt.Print("Deleting todo queue from redis ... ")
+			fmt.Print("Deleting todo queue from redis ... ")
 
-		err = stateManager.Reset()
-		if err != nil {
-			log.Fatal(err)
-		}
+			err = stateManager.Reset()
+			if err != nil {
+				log.Fatal(err)
+			}
 
-		fmt.Println("[done]")
+			fmt.Println("[done]")
+		}
 
 		fmt.Print("Truncating database ... ")
 
@@ -59,6 +63,7 @@ var resetCmd = &cobra.Command{
 			drop schema if exists smart_yield cascade;
 			drop schema if exists smart_exposure cascade;
 			drop schema if exists smart_alpha cascade;
+			drop schema if exists contract_events cascade;
 		`)
 
 		if err != nil {
@@ -76,6 +81,7 @@ func init() {
 
 	addDBFlags(resetCmd)
 	addRedisFlags(resetCmd)
+	addQueueFlags(resetCmd)
 
 	resetCmd.Flags().Bool("force", false, "Skip interactive shell")
 }
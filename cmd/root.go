@@ -7,6 +7,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"github.com/barnbridge/meminero/config"
@@ -14,6 +15,10 @@ import (
 
 var log = logrus.WithField("module", "main")
 
+// commandLineOnly marks the flags that are not bound to the config, e.g. because they would shadow a config section
+// with the same name; they are read from the command instead of viper
+const commandLineOnly = "command-line-only"
+
 var (
 	configFile string
 	version    bool
@@ -23,7 +28,7 @@ var (
 		Short: "Ethereum data and indexer",
 		Long:  "Scrape ethereum data from any web3-compatible node and index it into a postgres database",
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			err := viper.BindPFlags(cmd.Flags())
+			err := bindFlags(cmd)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -83,3 +88,18 @@ func init() {
 	// local flags;
 	RootCmd.Flags().BoolVar(&version, "version", false, "Display the current version of this CLI")
 }
+
+// bindFlags binds the flags of the command to the config, except the commandLineOnly ones
+func bindFlags(cmd *cobra.Command) error {
+	var err error
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if _, skip := f.Annotations[commandLineOnly]; skip || err != nil {
+			return
+		}
+
+		err = viper.BindPFlag(f.Name, f)
+	})
+
+	return err
+}
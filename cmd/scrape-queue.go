@@ -6,9 +6,13 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/barnbridge/meminero/config"
 	"github.com/barnbridge/meminero/db"
@@ -16,6 +20,7 @@ import (
 	"github.com/barnbridge/meminero/glue"
 	"github.com/barnbridge/meminero/integrity"
 	"github.com/barnbridge/meminero/state"
+	"github.com/barnbridge/meminero/state/leader"
 	"github.com/barnbridge/meminero/state/queuekeeper"
 )
 
@@ -23,9 +28,13 @@ var scrapeQueueCmd = &cobra.Command{
 	Use:   "queue",
 	Short: "Start the scraper as a long running process",
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
 
+		if config.Store.Feature.Finality.Dual && !config.Store.Feature.Integrity.Enabled {
+			log.Fatal("dual finality mode requires the integrity checker, which confirms the blocks once they are final")
+		}
+
 		listenOn := fmt.Sprintf(":%d", config.Store.Metrics.Port)
 		sm := http.NewServeMux()
 		sm.Handle("/metrics", promhttp.Handler())
@@ -63,9 +72,26 @@ var scrapeQueueCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
+		// every replica consumes tasks, but only the elected leader of each role runs the keeper and the integrity
+		// checker, so the head blocks and the checkpoints are not duplicated
+		elector, err := leader.New(d.Connection())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// the background loops are tracked so the shutdown can wait for them to return
+		var loops sync.WaitGroup
+		runLoop := func(fn func()) {
+			loops.Add(1)
+			go func() {
+				defer loops.Done()
+				fn()
+			}()
+		}
+
 		if config.Store.Feature.Integrity.Enabled {
 			integrityChecker := integrity.NewChecker(d.Connection(), tracker, state)
-			go integrityChecker.Run(ctx)
+			runLoop(func() { elector.Run(ctx, "integrity", integrityChecker.Run) })
 		}
 
 		if config.Store.Feature.QueueKeeper.Enabled {
@@ -73,21 +99,53 @@ var scrapeQueueCmd = &cobra.Command{
 			if err != nil {
 				log.Fatal(err)
 			}
-			go keeper.Run(ctx)
+			runLoop(func() { elector.Run(ctx, "queuekeeper", keeper.Run) })
 		}
 
-		g, err := glue.New(d.Connection(), state)
+		g, err := glue.New(d.Connection(), tracker, state)
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		go g.Run(ctx)
+		runLoop(func() { g.ReportQueue(ctx) })
 
-		// TODO i think listening to ctx done like this does not leave time for threads to exit cleanly
 		<-ctx.Done()
 
-		// cleanup
-		_ = metricsSrv.Close()
+		// shutdown: no new task is taken from now on, the block in flight is finished (or aborted and requeued if it
+		// takes longer than the deadline) and its lock released, then the background loops are stopped
+		timeout := viper.GetDuration("shutdown-timeout")
+		deadline := time.Now().Add(timeout)
+		log.Infof("shutting down (deadline %s)", timeout)
+
+		g.Stop(timeout)
+
+		loopsDone := make(chan struct{})
+		go func() {
+			loops.Wait()
+			close(loopsDone)
+		}()
+
+		select {
+		case <-loopsDone:
+		case <-time.After(time.Until(deadline)):
+			log.Warn("background loops did not stop before the deadline")
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Until(deadline))
+		defer cancel()
+
+		err = metricsSrv.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Warnf("could not stop metrics server cleanly: %s", err)
+		}
+
+		err = state.Close()
+		if err != nil {
+			log.Warnf("could not close task queue: %s", err)
+		}
+
+		d.Connection().Close()
 
 		log.Info("Work done. Goodbye!")
 	},
@@ -95,4 +153,6 @@ var scrapeQueueCmd = &cobra.Command{
 
 func init() {
 	scrapeCmd.AddCommand(scrapeQueueCmd)
+
+	scrapeQueueCmd.Flags().Duration("shutdown-timeout", 30*time.Second, "How long to wait for the block in flight to be finished on shutdown before aborting it")
 }
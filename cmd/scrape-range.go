@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/barnbridge/meminero/db"
+	"github.com/barnbridge/meminero/eth"
+	"github.com/barnbridge/meminero/glue"
+	"github.com/barnbridge/meminero/state"
+)
+
+var scrapeRangeCmd = &cobra.Command{
+	Use:   "range",
+	Short: "Scrape a range of blocks directly, without going through the queue (does not require redis)",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		from := viper.GetInt64("from")
+		to := viper.GetInt64("to")
+		if from < 0 || to < 0 {
+			log.Fatal("both --from and --to must be specified")
+		}
+
+		if from > to {
+			log.Fatal("--from must be lower than or equal to --to")
+		}
+
+		err := eth.Init()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		d, err := db.New()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = d.Migrate(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		g, err := glue.New(d.Connection(), nil, state.NewManagerWithoutQueue(d.Connection()))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		failed, err := g.ScrapeRange(ctx, from, to, viper.GetInt("workers"), viper.GetBool("resume"))
+		if err != nil && err != context.Canceled {
+			log.Fatal(err)
+		}
+
+		if len(failed) > 0 {
+			log.WithField("blocks", failed).Fatalf("%d blocks failed to be processed", len(failed))
+		}
+
+		if err == context.Canceled {
+			log.Info("interrupted; run again with --resume to continue")
+			return
+		}
+
+		log.Info("Work done. Goodbye!")
+	},
+}
+
+func init() {
+	scrapeCmd.AddCommand(scrapeRangeCmd)
+
+	scrapeRangeCmd.Flags().Int64("from", -1, "The first block of the range")
+	scrapeRangeCmd.Flags().Int64("to", -1, "The last block of the range, inclusive")
+	scrapeRangeCmd.Flags().Int("workers", 4, "Number of blocks to process in parallel")
+	scrapeRangeCmd.Flags().Bool("resume", true, "Skip the blocks that are already stored in the database")
+}
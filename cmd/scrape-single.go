@@ -43,7 +43,7 @@ var scrapeSingleCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
-		g, err := glue.New(d.Connection(), state)
+		g, err := glue.New(d.Connection(), nil, state)
 		if err != nil {
 			log.Fatal(err)
 		}
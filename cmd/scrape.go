@@ -17,6 +17,9 @@ func init() {
 
 	addDBFlags(scrapeCmd)
 	addRedisFlags(scrapeCmd)
+	addQueueFlags(scrapeCmd)
+	addRawStoreFlags(scrapeCmd)
+	addLeaderFlags(scrapeCmd)
 	addMetricsFlags(scrapeCmd)
 	addFeatureFlags(scrapeCmd)
 	addETHFlags(scrapeCmd)
@@ -31,4 +34,5 @@ func init() {
 	addStorableSmartExposureFlags(scrapeCmd)
 	addStorableSmartAlphaFlags(scrapeCmd)
 	addStorableTokenPricesFlags(scrapeCmd)
+	addStorableContractEventsFlags(scrapeCmd)
 }
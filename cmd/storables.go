@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/barnbridge/meminero/processor"
+)
+
+var storablesCmd = &cobra.Command{
+	Use:   "storables",
+	Short: "Inspect the storables",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.HelpFunc()(cmd, args)
+	},
+}
+
+var storablesGraphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Print the storables graph: the execution stages and the dependencies between the storables",
+	Run: func(cmd *cobra.Command, args []string) {
+		g, err := processor.StorableGraph()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if viper.GetBool("dot") {
+			fmt.Print(g.Dot())
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STORABLE\tSTAGE\tCONSUMES\tREQUIRES\tSAMPLING")
+		for i, ids := range g.Stages {
+			for _, id := range ids {
+				n := node(g, id)
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", n.ID, i, list(n.Consumes), list(n.Requires), orDash(n.Sampling))
+			}
+		}
+
+		for _, n := range g.Nodes {
+			if !n.Enabled {
+				fmt.Fprintf(w, "%s\tdisabled\t%s\t%s\t%s\n", n.ID, list(n.Consumes), list(n.Requires), orDash(n.Sampling))
+			}
+		}
+		w.Flush()
+	},
+}
+
+func node(g *processor.Graph, id string) processor.GraphNode {
+	for _, n := range g.Nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+
+	return processor.GraphNode{ID: id}
+}
+
+func list(ids []string) string {
+	return orDash(strings.Join(ids, ","))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+func init() {
+	RootCmd.AddCommand(storablesCmd)
+	storablesCmd.AddCommand(storablesGraphCmd)
+
+	storablesGraphCmd.Flags().Bool("dot", false, "Print the graph in the graphviz dot format")
+
+	addFeatureFlags(storablesCmd)
+	addStorableAccountERC20TransfersFlags(storablesCmd)
+	addStorableGovernanceFlags(storablesCmd)
+	addStorableMonitoredERC20TransfersFlags(storablesCmd)
+	addStorableBarnFlags(storablesCmd)
+	addStorableYieldFarmingFlags(storablesCmd)
+	addStorableSmartYieldFlags(storablesCmd)
+	addStorableSmartExposureFlags(storablesCmd)
+	addStorableSmartAlphaFlags(storablesCmd)
+	addStorableTokenPricesFlags(storablesCmd)
+	addStorableContractEventsFlags(storablesCmd)
+}
@@ -8,6 +8,9 @@ import (
 type store struct {
 	Database database `mapstructure:"db"`
 	Redis    redis    `mapstructure:"redis"`
+	Queue    queue    `mapstructure:"queue"`
+	RawStore rawStore `mapstructure:"raw-store"`
+	Leader   leader   `mapstructure:"leader-election"`
 	Metrics  metrics  `mapstructure:"metrics"`
 	API      api      `mapstructure:"api"`
 	Feature  features `mapstructure:"feature"`
@@ -24,4 +27,9 @@ func Load() {
 	if err != nil {
 		logrus.Fatal(err)
 	}
+
+	// the best block tracker only supports a single node, so it falls back to the first endpoint
+	if Store.ETH.Config.HTTP == "" && len(Store.ETH.Endpoints) > 0 {
+		Store.ETH.Config.HTTP = Store.ETH.Endpoints[0].URL
+	}
 }
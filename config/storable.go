@@ -10,6 +10,7 @@ type storable struct {
 	SmartYield            smartYield            `mapstructure:"smartYield"`
 	SmartAlpha            smartAlpha            `mapstructure:"smartAlpha"`
 	TokenPrices           tokenPrices           `mapstructure:"tokenPrices"`
+	ContractEvents        contractEvents        `mapstructure:"contractEvents"`
 }
 
 type accountERC20Transfers struct {
@@ -42,6 +43,8 @@ type smartExposure struct {
 	EPoolPeripheryAddress string
 	ETokenFactoryAddress  string
 	EPoolHelperAddress    string
+
+	Sampling Sampling
 }
 
 type smartYield struct {
@@ -51,12 +54,54 @@ type smartYield struct {
 	Rewards struct {
 		Factories string
 	}
+
+	Sampling Sampling
 }
 
 type tokenPrices struct {
 	Enabled bool
+
+	Sampling Sampling
 }
 
 type smartAlpha struct {
 	Enabled bool
+
+	Sampling Sampling
+}
+
+type contractEvents struct {
+	Enabled     bool
+	TypedTables bool `mapstructure:"typed-tables"`
+
+	Contracts []ContractEventsSource
+}
+
+// ContractEventsSource is a set of contracts sharing an ABI whose events are indexed by the contractEvents storable
+type ContractEventsSource struct {
+	// Name identifies the source in public.contract_events and in the names of the typed tables
+	Name      string
+	Addresses []string
+	// ABI is the name of the ABI JSON file in the `ethtypes.abi-folder` folder
+	ABI string
+	// Events are the names of the events to index; all the events of the ABI if empty
+	Events     []string
+	StartBlock int64 `mapstructure:"start-block"`
+}
+
+const (
+	SamplingEveryBlock = "every-block"
+	SamplingBlocks     = "blocks"
+	SamplingSeconds    = "seconds"
+	SamplingEvents     = "events"
+)
+
+// Sampling controls on which blocks a contract state storable takes a snapshot
+// - every-block: on all the blocks
+// - blocks: on the blocks whose number is a multiple of Interval
+// - seconds: on the first block of every Interval seconds of block time
+// - events: only on the blocks that contain logs emitted by the contracts of the storable
+type Sampling struct {
+	Mode     string
+	Interval int64
 }
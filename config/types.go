@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/lacasian/ethwheels/bestblock"
 )
 
@@ -23,6 +25,26 @@ type redis struct {
 	Password string
 }
 
+type queue struct {
+	Backend string
+	Weights struct {
+		Repair   int64
+		Backfill int64
+	}
+}
+
+type leader struct {
+	Enabled bool
+	Backend string
+	TTL     time.Duration
+}
+
+type rawStore struct {
+	Enabled bool
+	Backend string
+	Path    string
+}
+
 type metrics struct {
 	Port int64
 }
@@ -36,6 +58,11 @@ type api struct {
 type features struct {
 	Integrity struct {
 		Enabled bool
+		Data    struct {
+			Enabled   bool
+			MaxBlocks int64 `mapstructure:"max-blocks"`
+			ChunkSize int64 `mapstructure:"chunk-size"`
+		}
 	}
 	QueueKeeper struct {
 		Enabled bool
@@ -45,12 +72,58 @@ type features struct {
 	ContractState struct {
 		Enabled bool
 	} `mapstructure:"contract-state"`
+	Reorg struct {
+		MaxDepth int64 `mapstructure:"max-depth"`
+	}
+	Finality struct {
+		Mode string
+		Dual bool
+	}
 	RequeueFailedBlocks bool `mapstructure:"requeue-failed-blocks"`
+	Retry               struct {
+		MaxAttempts int64         `mapstructure:"max-attempts"`
+		Backoff     time.Duration `mapstructure:"backoff"`
+		MaxBackoff  time.Duration `mapstructure:"max-backoff"`
+	}
+	LogScraping bool `mapstructure:"log-scraping"`
+	Batch       struct {
+		Enabled   bool
+		Threshold int64
+		Size      int64
+	}
 }
 
 type eth struct {
 	bestblock.Config `mapstructure:"client"`
-	MaxBatch         int `mapstructure:"max-batch"`
+	MaxBatch         int           `mapstructure:"max-batch"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+
+	// Endpoints replaces `client.http` for the JSON-RPC calls when set; the requests are spread across the healthy
+	// endpoints according to their weights
+	Endpoints   []Endpoint `mapstructure:"endpoints"`
+	HealthCheck struct {
+		Interval time.Duration
+		MaxLag   int64 `mapstructure:"max-lag"`
+	} `mapstructure:"health-check"`
+
+	// Multicall aggregates the contract calls made at the same block through a Multicall2 contract (disabled if the
+	// address is empty)
+	Multicall struct {
+		Address      string
+		StartAtBlock int64 `mapstructure:"start-at-block"`
+		MaxCalls     int   `mapstructure:"max-calls"`
+		Wait         time.Duration
+	}
+}
+
+type Endpoint struct {
+	Name   string
+	URL    string
+	Weight int
+
+	// RateLimit is the maximum number of requests per second sent to this endpoint (0 means no limit)
+	RateLimit float64 `mapstructure:"rate-limit"`
+	Burst     int
 }
 
 type ethtypes struct {
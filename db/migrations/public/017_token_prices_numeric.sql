@@ -0,0 +1,40 @@
+-- prices are stored with full precision; existing rows are converted in place
+-- (the digits already lost by double precision can only be recovered by re-scraping the affected blocks)
+alter table public.token_prices
+    alter column price type numeric using price::numeric;
+
+drop function if exists public.token_usd_price_at_ts(text, bigint);
+
+create or replace function public.token_usd_price_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    _price numeric;
+begin
+    select into _price price
+    from public.token_prices p
+    where p.token_address = addr
+      and quote_asset = 'USD'
+      and block_timestamp <= ts
+    order by block_timestamp desc
+    limit 1;
+
+    return _price;
+end;
+$$;
+
+drop function if exists public.token_price_at_ts(text, text, bigint);
+
+create or replace function public.token_price_at_ts(addr text, quote text, ts bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select price
+             from public.token_prices p
+             where p.token_address = addr
+               and quote_asset = quote
+               and block_timestamp <= ts
+             order by block_timestamp desc
+             limit 1 );
+end;
+$$;
@@ -0,0 +1,12 @@
+-- task queue and block locks used when `queue.backend` is postgres
+create table public.block_tasks
+(
+    number     bigint primary key,
+    created_at timestamp default now()
+);
+
+create table public.block_locks
+(
+    key        text primary key,
+    expires_at timestamptz not null
+);
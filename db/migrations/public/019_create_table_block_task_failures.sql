@@ -0,0 +1,16 @@
+-- failed blocks are queued again with a delay
+alter table public.block_tasks
+    add column not_before timestamptz not null default now();
+
+create index block_tasks_not_before_idx on public.block_tasks (not_before);
+
+create table public.block_task_failures
+(
+    number     bigint primary key,
+    attempts   bigint      not null,
+    last_error text        not null,
+    failed_at  timestamptz not null,
+    dead       boolean     not null default false
+);
+
+create index block_task_failures_dead_idx on public.block_task_failures (number) where dead;
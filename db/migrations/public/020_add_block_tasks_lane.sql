@@ -0,0 +1,5 @@
+-- tasks are split in priority lanes (head, repair, backfill)
+alter table public.block_tasks
+    add column lane text not null default 'backfill';
+
+create index block_tasks_lane_number_idx on public.block_tasks (lane, number);
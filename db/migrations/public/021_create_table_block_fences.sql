@@ -0,0 +1,11 @@
+-- every lock acquisition gets a fencing token (a timestamp in microseconds), so a worker that lost its lock can't
+-- save a block anymore
+alter table public.block_locks
+    add column token bigint not null default 0;
+
+-- the highest token that saved (or rolled back) each block
+create table public.block_fences
+(
+    number bigint primary key,
+    token  bigint not null
+);
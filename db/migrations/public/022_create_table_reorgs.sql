@@ -0,0 +1,17 @@
+create table public.reorgs
+(
+    id              bigserial primary key,
+    -- the block whose processing detected the reorg
+    detected_at     bigint      not null,
+    -- the highest stored block that is still part of the canonical chain
+    common_ancestor bigint      not null,
+    -- number of orphaned blocks that were rolled back
+    depth           bigint      not null,
+    -- hashes of the orphaned blocks and of the blocks replacing them, ordered by block number starting at
+    -- common_ancestor + 1 (empty if the node does not have the replacement yet)
+    old_hashes      text[]      not null,
+    new_hashes      text[]      not null,
+    created_at      timestamptz not null default now()
+);
+
+create index reorgs_detected_at_idx on public.reorgs (detected_at);
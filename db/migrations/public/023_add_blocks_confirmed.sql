@@ -0,0 +1,5 @@
+-- in dual finality mode the head blocks are stored before they are final and confirmed later by the integrity checker
+alter table public.blocks
+    add column confirmed boolean not null default true;
+
+create index blocks_unconfirmed_idx on public.blocks (number) where not confirmed;
@@ -0,0 +1,16 @@
+create table public.data_discrepancies
+(
+    id          bigserial primary key,
+    block       bigint      not null,
+    -- the storable whose events were checked (see integrity.dataChecks)
+    check_name  text        not null,
+    -- number of logs on chain that should be stored and number of logs stored, by (tx_hash, log_index)
+    chain_logs  bigint      not null,
+    stored_logs bigint      not null,
+    -- "tx_hash:log_index" of the logs that were not stored and of the stored rows that point to no log on chain
+    missing     text[]      not null,
+    unexpected  text[]      not null,
+    created_at  timestamptz not null default now()
+);
+
+create index data_discrepancies_block_idx on public.data_discrepancies (block);
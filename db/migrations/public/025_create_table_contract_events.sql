@@ -0,0 +1,22 @@
+create table public.contract_events
+(
+    -- the name of the contract events source in the config
+    source            text    not null,
+    contract_address  text    not null,
+    event_name        text    not null,
+    -- the canonical signature of the event, e.g. Transfer(address,address,uint256)
+    event_signature   text    not null,
+    -- the decoded arguments by name; the numbers are decimal strings and the bytes are hex strings
+    args              jsonb   not null,
+
+    block_timestamp   bigint  not null,
+    included_in_block bigint  not null,
+    tx_hash           text    not null,
+    tx_index          integer not null,
+    log_index         integer not null,
+    created_at        timestamp default now()
+);
+
+create index contract_events_source_event_idx on public.contract_events (source, event_name, included_in_block desc);
+create index contract_events_contract_address_idx on public.contract_events (contract_address, included_in_block desc);
+create index contract_events_included_in_block_idx on public.contract_events (included_in_block);
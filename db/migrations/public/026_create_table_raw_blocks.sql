@@ -0,0 +1,11 @@
+create table public.raw_blocks
+(
+    number     bigint      not null,
+    -- without the 0x prefix, like public.blocks
+    block_hash text        not null,
+    -- the gzipped json of the block, its transactions and their receipts
+    data       bytea       not null,
+    created_at timestamptz not null default now(),
+
+    primary key (number, block_hash)
+);
@@ -0,0 +1,398 @@
+-- public.token_prices.price is numeric; keep the whole chain of value functions in numeric so no precision is lost
+drop function if exists smart_alpha.junior_token_to_usd_at_ts(text, numeric, bigint);
+drop function if exists smart_alpha.senior_token_to_usd_at_ts(text, numeric, bigint);
+drop function if exists smart_alpha.junior_portfolio_value_at_ts(text, bigint);
+drop function if exists smart_alpha.senior_portfolio_value_at_ts(text, bigint);
+drop function if exists smart_alpha.estimated_junior_token_price_at_ts(text, bigint);
+drop function if exists smart_alpha.estimated_senior_token_price_at_ts(text, bigint);
+drop function if exists smart_alpha.underlying_to_junior_tokens_at_epoch(text, numeric, bigint);
+drop function if exists smart_alpha.underlying_to_senior_tokens_at_epoch(text, numeric, bigint);
+drop function if exists smart_alpha.junior_tokens_to_underlying_at_epoch(text, numeric, bigint);
+drop function if exists smart_alpha.senior_tokens_to_underlying_at_epoch(text, numeric, bigint);
+drop function if exists smart_alpha.entry_queue_portfolio_value_at_ts(text, bigint);
+drop function if exists smart_alpha.exit_queue_portfolio_value_at_ts(text, bigint);
+drop function if exists smart_alpha.pool_tvl(text);
+drop function if exists smart_alpha.pool_tvl_v2(text);
+drop function if exists smart_alpha.performance_at_ts(text, bigint);
+
+create or replace function smart_alpha.junior_token_to_usd_at_ts(token_address text, amount numeric(78), ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    pool_token_decimals integer;
+    pool_token_address  text;
+    _pool_address       text;
+begin
+    select into pool_token_decimals,pool_token_address, _pool_address p.pool_token_decimals,
+                                                                      p.pool_token_address,
+                                                                      p.pool_address
+    from smart_alpha.pools p
+    where p.junior_token_address = token_address;
+
+    return ( select amount::numeric / pow(10::numeric, pool_token_decimals) *
+                    ( select estimated_junior_token_price::numeric / pow(10::numeric, 18)
+                      from smart_alpha.pool_state
+                      where pool_address = _pool_address
+                        and block_timestamp <= ts
+                      order by block_timestamp desc
+                      limit 1 ) * ( select public.token_usd_price_at_ts(pool_token_address, ts) ) );
+end;
+$$;
+
+create or replace function smart_alpha.senior_token_to_usd_at_ts(token_address text, amount numeric(78), ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    pool_token_decimals integer;
+    pool_token_address  text;
+    _pool_address       text;
+begin
+    select into pool_token_decimals,pool_token_address, _pool_address p.pool_token_decimals,
+                                                                      p.pool_token_address,
+                                                                      p.pool_address
+    from smart_alpha.pools p
+    where p.senior_token_address = token_address;
+
+    return ( select amount::numeric / pow(10::numeric, pool_token_decimals) *
+                    ( select estimated_senior_token_price::numeric / pow(10::numeric, 18)
+                      from smart_alpha.pool_state
+                      where pool_address = _pool_address
+                        and block_timestamp <= ts
+                      order by block_timestamp desc
+                      limit 1 ) * ( select public.token_usd_price_at_ts(pool_token_address, ts) ) );
+end;
+$$;
+
+create or replace function smart_alpha.junior_portfolio_value_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select sum(smart_alpha.junior_token_to_usd_at_ts(token_address, balance, ts))
+             from public.erc20_balances_at_ts(addr, ( select array_agg(junior_token_address) from smart_alpha.pools ),
+                                              ts) );
+end;
+$$;
+
+create or replace function smart_alpha.senior_portfolio_value_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select sum(smart_alpha.senior_token_to_usd_at_ts(token_address, balance, ts))
+             from public.erc20_balances_at_ts(addr, ( select array_agg(senior_token_address) from smart_alpha.pools ),
+                                              ts) );
+end;
+$$;
+
+create or replace function smart_alpha.estimated_junior_token_price_at_ts(pool text, ts bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return (( select estimated_junior_token_price
+              from smart_alpha.pool_state ps
+              where ps.pool_address = pool
+                and ps.block_timestamp <= ts
+              order by ps.block_timestamp desc
+              limit 1 ) / pow(10::numeric, 18));
+end;
+$$;
+
+create or replace function smart_alpha.estimated_senior_token_price_at_ts(pool text, ts bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return (( select estimated_senior_token_price
+              from smart_alpha.pool_state ps
+              where ps.pool_address = pool
+                and ps.block_timestamp <= ts
+              order by ps.block_timestamp desc
+              limit 1 ) / pow(10::numeric, 18));
+end;
+$$;
+
+create or replace function smart_alpha.underlying_to_junior_tokens_at_epoch(pool text, amount numeric(78, 18), _epoch bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select amount / pow(10::numeric, p.pool_token_decimals) /
+                    (pei.junior_token_price_start::numeric / pow(10::numeric, 18))
+             from smart_alpha.pool_epoch_info pei
+                      inner join smart_alpha.pools p on p.pool_address = pool
+             where pei.pool_address = pool
+               and pei.epoch_id > _epoch
+             order by pei.epoch_id
+             limit 1 );
+end;
+$$;
+
+create or replace function smart_alpha.underlying_to_senior_tokens_at_epoch(pool text, amount numeric(78, 18), _epoch bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select amount / pow(10::numeric, p.pool_token_decimals) /
+                    (pei.senior_token_price_start::numeric / pow(10::numeric, 18))
+             from smart_alpha.pool_epoch_info pei
+                      inner join smart_alpha.pools p on p.pool_address = pool
+             where pei.pool_address = pool
+               and pei.epoch_id > _epoch
+             order by pei.epoch_id
+             limit 1 );
+end;
+$$;
+
+create or replace function smart_alpha.junior_tokens_to_underlying_at_epoch(pool text, amount numeric(78, 18), _epoch bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select amount / pow(10::numeric, p.pool_token_decimals) *
+                    (pei.junior_token_price_start::numeric / pow(10::numeric, 18))
+             from smart_alpha.pool_epoch_info pei
+                      inner join smart_alpha.pools p on p.pool_address = pool
+             where pei.pool_address = pool
+               and pei.epoch_id > _epoch
+             order by pei.epoch_id
+             limit 1 );
+end;
+$$;
+
+create or replace function smart_alpha.senior_tokens_to_underlying_at_epoch(pool text, amount numeric(78, 18), _epoch bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select amount / pow(10::numeric, p.pool_token_decimals) *
+                    (pei.senior_token_price_start::numeric / pow(10::numeric, 18))
+             from smart_alpha.pool_epoch_info pei
+                      inner join smart_alpha.pools p on p.pool_address = pool
+             where pei.pool_address = pool
+               and pei.epoch_id > _epoch
+             order by pei.epoch_id
+             limit 1 );
+end;
+$$;
+
+create or replace function smart_alpha.entry_queue_portfolio_value_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select sum(case when j.epoch_id = ( select smart_alpha.pool_active_epoch_at_ts(j.pool_address, ts) ) then
+                                         underlying_in::numeric / pow(10::numeric, p.pool_token_decimals) *
+                                         public.token_usd_price_at_ts(p.pool_token_address, ts)
+                             else case when j.tranche = 'JUNIOR'
+                                           then ( select smart_alpha.underlying_to_junior_tokens_at_epoch(
+                                                                 j.pool_address, j.underlying_in::numeric(78, 18),
+                                                                 j.epoch_id) *
+                                                         ( select smart_alpha.estimated_junior_token_price_at_ts(j.pool_address, ts) ) *
+                                                         ( select public.token_usd_price_at_ts(p.pool_token_address, ts) ) )
+                                       when j.tranche = 'SENIOR'
+                                           then ( select smart_alpha.underlying_to_senior_tokens_at_epoch(
+                                                                 j.pool_address, j.underlying_in::numeric(78, 18),
+                                                                 j.epoch_id) *
+                                                         ( select smart_alpha.estimated_senior_token_price_at_ts(j.pool_address, ts) ) *
+                                                         ( select public.token_usd_price_at_ts(p.pool_token_address, ts) ) ) end end)
+             from smart_alpha.user_join_entry_queue_events j
+                      left join smart_alpha.user_redeem_tokens_events r
+                                on j.user_address = r.user_address and j.pool_address = r.pool_address and
+                                   j.epoch_id = r.epoch_id and j.tranche = r.tranche and r.block_timestamp <= ts
+                      inner join smart_alpha.pools p on j.pool_address = p.pool_address
+             where j.user_address = addr
+               and j.block_timestamp <= ts
+               and r.user_address is null );
+end;
+$$;
+
+create or replace function smart_alpha.exit_queue_portfolio_value_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+begin
+    return ( select sum(case when j.epoch_id = ( select smart_alpha.pool_active_epoch_at_ts(j.pool_address, ts) )
+                                 then case when j.tranche = 'JUNIOR' then ( select j.tokens_in::numeric /
+                                                                                   pow(10::numeric, p.pool_token_decimals) *
+                                                                                   ( select smart_alpha.estimated_junior_token_price_at_ts(j.pool_address, ts) ) *
+                                                                                   ( select public.token_usd_price_at_ts(p.pool_token_address, ts) ) )
+                                           when j.tranche = 'SENIOR' then ( select j.tokens_in::numeric /
+                                                                                   pow(10::numeric, p.pool_token_decimals) *
+                                                                                   ( select smart_alpha.estimated_senior_token_price_at_ts(j.pool_address, ts) ) *
+                                                                                   ( select public.token_usd_price_at_ts(p.pool_token_address, ts) ) ) end
+                             else case when j.tranche = 'JUNIOR' then
+                                               ( select smart_alpha.junior_tokens_to_underlying_at_epoch(j.pool_address,
+                                                                                                         j.tokens_in::numeric(78, 18),
+                                                                                                         j.epoch_id) ) *
+                                               ( select public.token_usd_price_at_ts(p.pool_token_address, ts) )
+                                       when j.tranche = 'SENIOR' then
+                                               ( select smart_alpha.senior_tokens_to_underlying_at_epoch(j.pool_address,
+                                                                                                         j.tokens_in::numeric(78, 18),
+                                                                                                         j.epoch_id) ) *
+                                               ( select public.token_usd_price_at_ts(p.pool_token_address, ts) ) end end)
+             from smart_alpha.user_join_exit_queue_events j
+                      left join smart_alpha.user_redeem_underlying_events r
+                                on j.user_address = r.user_address and j.pool_address = r.pool_address and
+                                   j.epoch_id = r.epoch_id and j.tranche = r.tranche and r.block_timestamp <= ts
+                      inner join smart_alpha.pools p on j.pool_address = p.pool_address
+             where j.user_address = addr
+               and j.block_timestamp <= ts
+               and r.user_address is null );
+end;
+$$;
+
+create or replace function smart_alpha.pool_tvl(pool text)
+    returns table
+            (
+                epoch_junior_tvl       numeric,
+                epoch_senior_tvl       numeric,
+                junior_entry_queue_tvl numeric,
+                senior_entry_queue_tvl numeric,
+                junior_exited_tvl      numeric,
+                senior_exited_tvl      numeric
+            )
+    language plpgsql
+as
+$$
+declare
+    token_price    numeric;
+    token_address  text;
+    token_decimals integer;
+    epoch_jl       numeric(78, 18);
+    epoch_sl       numeric(78, 18);
+    j_entry        numeric(78, 18);
+    j_exit         numeric(78, 18);
+    s_entry        numeric(78, 18);
+    s_exit         numeric(78, 18);
+begin
+    select into token_address, token_decimals p.pool_token_address, p.pool_token_decimals
+    from smart_alpha.pools p
+    where pool_address = pool;
+
+    select into token_price public.token_usd_price_at_ts(token_address, ( select extract(epoch from now())::bigint ));
+
+    select into epoch_jl, epoch_sl junior_liquidity, senior_liquidity
+    from smart_alpha.pool_epoch_info
+    where pool_address = pool
+    order by epoch_id desc
+    limit 1;
+
+    select into j_entry, j_exit, s_entry, s_exit queued_juniors_underlying_in,
+                                                 queued_juniors_underlying_out,
+                                                 queued_seniors_underlying_in,
+                                                 queued_seniors_underlying_out
+    from smart_alpha.pool_state
+    where pool_address = pool
+    order by block_timestamp desc
+    limit 1;
+
+    return query select coalesce(epoch_jl, 0) / pow(10::numeric, token_decimals) * token_price as epoch_junior_tvl,
+                        coalesce(epoch_sl, 0) / pow(10::numeric, token_decimals) * token_price as epoch_senior_tvl,
+                        coalesce(j_entry, 0) / pow(10::numeric, token_decimals) * token_price  as junior_entry_queue_tvl,
+                        coalesce(s_entry, 0) / pow(10::numeric, token_decimals) * token_price  as senior_entry_queue_tvl,
+                        coalesce(j_exit, 0) / pow(10::numeric, token_decimals) * token_price   as junior_exited_tvl,
+                        coalesce(s_exit, 0) / pow(10::numeric, token_decimals) * token_price   as senior_exited_tvl;
+end
+$$;
+
+create or replace function smart_alpha.pool_tvl_v2(pool text)
+    returns table
+            (
+                epoch_junior_tvl       numeric,
+                epoch_senior_tvl       numeric,
+                junior_entry_queue_tvl numeric,
+                senior_entry_queue_tvl numeric,
+                junior_exit_queue_tvl  numeric,
+                senior_exit_queue_tvl  numeric,
+                junior_exited_tvl      numeric,
+                senior_exited_tvl      numeric
+            )
+    language plpgsql
+as
+$$
+declare
+    token_price    numeric;
+    token_address  text;
+    token_decimals integer;
+    epoch_jl       numeric(78, 18);
+    epoch_sl       numeric(78, 18);
+    j_entry        numeric(78, 18);
+    jtokens_burn   numeric(78, 18);
+    j_exit         numeric(78, 18);
+    s_entry        numeric(78, 18);
+    stokens_burn   numeric(78, 18);
+    s_exit         numeric(78, 18);
+begin
+    select into token_address, token_decimals p.pool_token_address, p.pool_token_decimals
+    from smart_alpha.pools p
+    where pool_address = pool;
+
+    select into token_price public.token_usd_price_at_ts(token_address, ( select extract(epoch from now())::bigint ));
+
+    select into epoch_jl, epoch_sl junior_liquidity, senior_liquidity
+    from smart_alpha.pool_epoch_info
+    where pool_address = pool
+    order by epoch_id desc
+    limit 1;
+
+    select into j_entry, jtokens_burn, j_exit, s_entry, stokens_burn, s_exit queued_juniors_underlying_in,
+                                                                             queued_junior_tokens_burn / pow(10::numeric, 18) * estimated_junior_token_price,
+                                                                             queued_juniors_underlying_out,
+                                                                             queued_seniors_underlying_in,
+                                                                             queued_senior_tokens_burn / pow(10::numeric, 18) * estimated_senior_token_price,
+                                                                             queued_seniors_underlying_out
+    from smart_alpha.pool_state
+    where pool_address = pool
+    order by block_timestamp desc
+    limit 1;
+
+    return query select coalesce(epoch_jl, 0) / pow(10::numeric, token_decimals) * token_price     as epoch_junior_tvl,
+                        coalesce(epoch_sl, 0) / pow(10::numeric, token_decimals) * token_price     as epoch_senior_tvl,
+                        coalesce(j_entry, 0) / pow(10::numeric, token_decimals) * token_price      as junior_entry_queue_tvl,
+                        coalesce(s_entry, 0) / pow(10::numeric, token_decimals) * token_price      as senior_entry_queue_tvl,
+                        coalesce(jtokens_burn, 0) / pow(10::numeric, token_decimals) * token_price as junior_exit_queue_tvl,
+                        coalesce(stokens_burn, 0) / pow(10::numeric, token_decimals) * token_price as senior_exit_queue_tvl,
+                        coalesce(j_exit, 0) / pow(10::numeric, token_decimals) * token_price       as junior_exited_tvl,
+                        coalesce(s_exit, 0) / pow(10::numeric, token_decimals) * token_price       as senior_exited_tvl;
+end
+$$;
+
+create or replace function smart_alpha.performance_at_ts(pool text, ts bigint)
+    returns table
+            (
+                senior_without_sa numeric,
+                senior_with_sa    numeric,
+                junior_without_sa numeric,
+                junior_with_sa    numeric
+            )
+    language plpgsql
+as
+$$
+declare
+    token_price           numeric;
+    token_address         text;
+    quote_asset_symbol    text;
+    jtoken_price_start    numeric(78, 18);
+    stoken_price_start    numeric(78, 18);
+    jtoken_price_estimate numeric(78, 18);
+    stoken_price_estimate numeric(78, 18);
+begin
+    select into token_address, quote_asset_symbol p.pool_token_address,
+                                                  p.oracle_asset_symbol
+    from smart_alpha.pools p
+    where pool_address = pool;
+
+    select into token_price public.token_price_at_ts(token_address, quote_asset_symbol, ts);
+
+    select into jtoken_price_start, stoken_price_start junior_token_price_start, senior_token_price_start
+    from smart_alpha.pool_epoch_info
+    where pool_address = pool
+      and block_timestamp <= ts
+    order by block_timestamp desc
+    limit 1;
+
+    select into jtoken_price_estimate, stoken_price_estimate estimated_junior_token_price, estimated_senior_token_price
+    from smart_alpha.pool_state
+    where pool_address = pool
+      and block_timestamp <= ts
+    order by block_timestamp desc
+    limit 1;
+
+    return query select token_price                                                as senior_without_sa,
+                        1::numeric / stoken_price_start * stoken_price_estimate * token_price as senior_with_sa,
+                        token_price                                                as junior_without_sa,
+                        1::numeric / jtoken_price_start * jtoken_price_estimate * token_price as junior_with_sa;
+end
+$$;
@@ -0,0 +1,23 @@
+-- public.token_prices.price is numeric; the chart has to return the same type since `return query` does not cast
+drop function if exists smart_exposure.get_token_price_chart(text, bigint, text);
+
+create function smart_exposure.get_token_price_chart(_token_address text, start bigint, _date_trunc text)
+    returns TABLE
+            (
+                point       timestamp without time zone,
+                token_price numeric
+            )
+    language plpgsql
+as
+$$
+begin
+    return query select date_trunc(_date_trunc, to_timestamp(block_timestamp)::date)::timestamp as point,
+                        avg(price)                                                              as token_price
+                 from public.token_prices
+                 where token_address = _token_address
+                   and quote_asset = 'USD'
+                   and block_timestamp > start
+                 group by point
+                 order by point;
+end
+$$;
@@ -0,0 +1,165 @@
+-- public.token_prices.price is numeric; keep the portfolio value functions in numeric so no precision is lost
+drop function if exists smart_yield.senior_portfolio_value_at_ts(text, bigint);
+drop function if exists smart_yield.senior_underlying_price_at_ts(text, bigint);
+drop function if exists smart_yield.junior_portfolio_value_at_ts(text, bigint);
+drop function if exists smart_yield.junior_staked_balance_at_ts(text, bigint);
+drop function if exists smart_yield.jtoken_price_scaled_at_ts(text, bigint);
+drop function if exists smart_yield.junior_locked_balance_at_ts(text, bigint);
+drop function if exists smart_yield.junior_underlying_price_at_ts(text, bigint);
+drop function if exists smart_yield.junior_active_balance_at_ts(text, bigint);
+drop function if exists smart_yield.pool_underlying_price_at_ts(text, bigint);
+
+create or replace function smart_yield.senior_underlying_price_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    _price numeric;
+begin
+    select into _price public.token_usd_price_at_ts(
+                              ( select underlying_address from smart_yield.pools where senior_bond_address = addr ),
+                              ts);
+
+    return _price;
+end;
+$$;
+
+create or replace function smart_yield.senior_portfolio_value_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    value numeric;
+begin
+    select into value coalesce(sum(smart_yield.senior_bond_value_at_ts(token_address, token_id, ts)::numeric /
+                                   pow(10::numeric, ( select underlying_decimals
+                                                      from smart_yield.pools
+                                                      where senior_bond_address = token_address )) *
+                                   smart_yield.senior_underlying_price_at_ts(token_address, ts)),
+                               0) as senior_portfolio_value
+    from smart_yield.senior_portfolio_at_ts(addr, ts);
+
+    return value;
+end;
+$$;
+
+create or replace function smart_yield.pool_underlying_price_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    price numeric;
+begin
+    select into price public.token_usd_price_at_ts(
+                              ( select underlying_address from smart_yield.pools where pool_address = addr ), ts);
+
+    return price;
+end;
+$$;
+
+create or replace function smart_yield.junior_active_balance_at_ts(user_address text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    total_balance numeric;
+begin
+    select into total_balance sum(balance::numeric / pow(10::numeric, ( select underlying_decimals
+                                                                        from smart_yield.pools
+                                                                        where pool_address = pool
+                                                                        limit 1 )) *
+                                  ( select jtoken_price / pow(10::numeric, 18)
+                                    from smart_yield.pool_state
+                                    where pool_address = pool
+                                      and block_timestamp <= ts
+                                    order by block_timestamp desc
+                                    limit 1 ) *
+                                  ( select smart_yield.pool_underlying_price_at_ts(pool, ts) ))
+    from smart_yield.junior_active_positions_at_ts(user_address, ts);
+
+    return total_balance;
+end;
+$$;
+
+create or replace function smart_yield.junior_underlying_price_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    price numeric;
+begin
+    select into price public.token_usd_price_at_ts(
+                              ( select underlying_address from smart_yield.pools where junior_bond_address = addr ),
+                              ts);
+
+    return price;
+end;
+$$;
+
+create or replace function smart_yield.junior_locked_balance_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    value numeric;
+begin
+    select into value coalesce(sum(smart_yield.junior_bond_value_at_ts(token_address, token_id, ts)::numeric /
+                                   pow(10::numeric, ( select underlying_decimals
+                                                      from smart_yield.pools
+                                                      where junior_bond_address = token_address )) *
+                                   ( select jtoken_price / pow(10::numeric, 18)
+                                     from smart_yield.pool_state
+                                     where pool_address = ( select pool_address
+                                                            from smart_yield.pools
+                                                            where junior_bond_address = token_address )
+                                       and block_timestamp <= ts
+                                     order by block_timestamp desc
+                                     limit 1 ) * smart_yield.junior_underlying_price_at_ts(token_address, ts)), 0)
+    from smart_yield.junior_locked_positions_at_ts(addr, ts);
+
+    return value;
+end;
+$$;
+
+create or replace function smart_yield.jtoken_price_scaled_at_ts(sy_address text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    value numeric;
+begin
+    select into value jtoken_price / pow(10::numeric, 18)
+    from smart_yield.pool_state
+    where pool_address = sy_address
+      and block_timestamp <= ts
+    order by block_timestamp desc
+    limit 1;
+
+    return value;
+end;
+$$;
+
+create or replace function smart_yield.junior_staked_balance_at_ts(user_address text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    value numeric;
+begin
+    select into value sum(smart_yield.staked_amount_at_ts_by_reward_pool(pool_address, user_address, ts)::numeric /
+                          pow(10::numeric, ( select underlying_decimals
+                                             from smart_yield.pools as p
+                                             where p.pool_address = rp.pool_token_address )) *
+                          smart_yield.jtoken_price_scaled_at_ts(pool_token_address, ts) *
+                          smart_yield.pool_underlying_price_at_ts(pool_token_address, ts))
+    from smart_yield.reward_pools as rp;
+
+    return value;
+end;
+$$;
+
+create or replace function smart_yield.junior_portfolio_value_at_ts(addr text, ts bigint) returns numeric
+    language plpgsql as
+$$
+declare
+    value numeric;
+begin
+    select into value coalesce(smart_yield.junior_locked_balance_at_ts(addr, ts), 0) +
+                      coalesce(smart_yield.junior_active_balance_at_ts(addr, ts), 0) +
+                      coalesce(smart_yield.junior_staked_balance_at_ts(addr, ts), 0);
+
+    return value;
+end;
+$$;
@@ -0,0 +1,50 @@
+package eth
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/alethio/web3-go/ethrpc"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/utils"
+)
+
+// GetBlockTimestamp returns the timestamp of the given block without fetching its transactions
+func GetBlockTimestamp(block int64) (int64, error) {
+	var header struct {
+		Timestamp string
+	}
+
+	err := instance.ethrpc.MakeRequest(&header, ethrpc.ETHGetBlockByNumber, fmt.Sprintf("0x%x", block), false)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not get block %d", block)
+	}
+
+	ts, err := strconv.ParseInt(header.Timestamp, 0, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not decode block timestamp")
+	}
+
+	return ts, nil
+}
+
+// GetBlockHashes returns the hash and the parent hash of the given block (without the 0x prefix); the hashes are empty
+// if the node does not have the block
+func GetBlockHashes(block int64) (string, string, error) {
+	var header *struct {
+		Hash       string
+		ParentHash string
+	}
+
+	err := instance.ethrpc.MakeRequest(&header, ethrpc.ETHGetBlockByNumber, fmt.Sprintf("0x%x", block), false)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "could not get block %d", block)
+	}
+
+	if header == nil {
+		return "", "", nil
+	}
+
+	return utils.Trim0x(header.Hash), utils.Trim0x(header.ParentHash), nil
+}
@@ -11,6 +11,10 @@ import (
 	"github.com/barnbridge/meminero/utils"
 )
 
+// CallContractFunction returns a function that calls a view method of a contract and decodes the output into result;
+// if a block number is passed in opts, the call is executed at that block and, when multicall is enabled, it is
+// aggregated with the other calls made at the same block
+// Calls that revert return an error that satisfies IsReverted.
 func CallContractFunction(a abi.ABI, addr string, methodName string, methodArgs []interface{}, result interface{}, opts ...interface{}) func() error {
 	return func() error {
 		input, err := ABIGenerateInput(a, methodName, methodArgs...)
@@ -20,7 +24,7 @@ func CallContractFunction(a abi.ABI, addr string, methodName string, methodArgs
 		var data string
 
 		if len(opts) > 0 {
-			data, err = CallRawAtBlock(addr, input, opts[0].(int64))
+			data, err = callAtBlock(addr, input, opts[0].(int64))
 		} else {
 			data, err = CallRaw(addr, input)
 		}
@@ -38,17 +42,30 @@ func CallContractFunction(a abi.ABI, addr string, methodName string, methodArgs
 	}
 }
 
+// callAtBlock sends the call through the multicall contract if it's enabled and deployed at the given block
+func callAtBlock(address string, fnc string, block int64) (string, error) {
+	if instance.multicall != nil && block >= instance.multicall.startAtBlock {
+		return instance.multicall.call(address, fnc, block)
+	}
+
+	return CallRawAtBlock(address, fnc, block)
+}
+
 func CallRawAtBlock(address string, fnc string, block int64) (string, error) {
+	return callRawAtBlock(address, fnc, block, ethrpc.DefaultCallGas)
+}
+
+func callRawAtBlock(address string, fnc string, block int64, gas string) (string, error) {
 	var result string
 
 	obj := make(map[string]string)
 	obj["to"] = address
 	obj["data"] = fnc
-	obj["gas"] = ethrpc.DefaultCallGas
+	obj["gas"] = gas
 
 	err := instance.ethrpc.MakeRequest(&result, ethrpc.ETHCall, obj, fmt.Sprintf("0x%x", block))
 	if err != nil {
-		return "", errors.Wrapf(err, "could not make rpc request (%s.%s)", address, fnc)
+		return "", errors.Wrapf(checkReverted(err), "could not make rpc request (%s.%s)", address, fnc)
 	}
 
 	if result == "0x" {
@@ -68,7 +85,7 @@ func CallRaw(address string, fnc string) (string, error) {
 
 	err := instance.ethrpc.MakeRequest(&result, ethrpc.ETHCall, obj, "latest")
 	if err != nil {
-		return "", errors.Wrapf(err, "could not make rpc request (%s.%s)", address, fnc)
+		return "", errors.Wrapf(checkReverted(err), "could not make rpc request (%s.%s)", address, fnc)
 	}
 
 	if result == "0x" {
@@ -1,17 +1,16 @@
 package eth
 
 import (
-	"time"
-
 	"github.com/alethio/web3-go/ethrpc"
-	"github.com/alethio/web3-go/ethrpc/provider/httprpc"
 	"github.com/pkg/errors"
 
 	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/eth/rpcpool"
 )
 
 type conn struct {
-	ethrpc *ethrpc.ETH
+	ethrpc    *ethrpc.ETH
+	multicall *multicaller
 }
 
 var instance *conn
@@ -21,17 +20,12 @@ func Init() error {
 		return nil
 	}
 
-	batchLoader, err := httprpc.NewBatchLoader(config.Store.ETH.MaxBatch, 4*time.Millisecond)
-	if err != nil {
-		return errors.Wrap(err, "could not init batch loader")
-	}
-
-	provider, err := httprpc.NewWithLoader(config.Store.ETH.HTTP, batchLoader)
+	pool, err := rpcpool.Init()
 	if err != nil {
-		return errors.Wrap(err, "could not init ethprc http provider")
+		return errors.Wrap(err, "could not init rpc pool")
 	}
 
-	eth, err := ethrpc.New(provider)
+	eth, err := ethrpc.New(pool)
 	if err != nil {
 		return errors.Wrap(err, "could not create ethrpc")
 	}
@@ -40,5 +34,10 @@ func Init() error {
 		ethrpc: eth,
 	}
 
+	if config.Store.ETH.Multicall.Address != "" {
+		instance.multicall = newMulticaller(config.Store.ETH.Multicall.Address, config.Store.ETH.Multicall.StartAtBlock,
+			config.Store.ETH.Multicall.MaxCalls, config.Store.ETH.Multicall.Wait)
+	}
+
 	return nil
 }
@@ -0,0 +1,74 @@
+package eth
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alethio/web3-go/ethrpc"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+const (
+	// FinalityLag considers final the blocks that are `feature.queuekeeper.lag` blocks behind the best block
+	FinalityLag = "lag"
+	// FinalitySafe and FinalityFinalized use the block tags of the node
+	FinalitySafe      = "safe"
+	FinalityFinalized = "finalized"
+)
+
+// finalityCacheTTL is how long the block of the finality tag is reused before asking the node again; the tags move
+// once per slot at most
+const finalityCacheTTL = 5 * time.Second
+
+var finality struct {
+	mu        sync.Mutex
+	block     int64
+	fetchedAt time.Time
+}
+
+// FinalizedHeight returns the highest block considered final under `feature.finality.mode`, given the best block
+func FinalizedHeight(best int64) (int64, error) {
+	switch config.Store.Feature.Finality.Mode {
+	case "", FinalityLag:
+		return best - config.Store.Feature.QueueKeeper.Lag, nil
+	case FinalitySafe, FinalityFinalized:
+		return taggedBlock(config.Store.Feature.Finality.Mode)
+	default:
+		return 0, errors.Errorf("unknown finality mode: %s", config.Store.Feature.Finality.Mode)
+	}
+}
+
+func taggedBlock(tag string) (int64, error) {
+	finality.mu.Lock()
+	defer finality.mu.Unlock()
+
+	if time.Since(finality.fetchedAt) < finalityCacheTTL {
+		return finality.block, nil
+	}
+
+	var header *struct {
+		Number string
+	}
+
+	err := instance.ethrpc.MakeRequest(&header, ethrpc.ETHGetBlockByNumber, tag, false)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not get %s block", tag)
+	}
+
+	if header == nil {
+		return 0, errors.Errorf("node returned no %s block", tag)
+	}
+
+	n, err := strconv.ParseInt(header.Number, 0, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not decode %s block number", tag)
+	}
+
+	finality.block = n
+	finality.fetchedAt = time.Now()
+
+	return n, nil
+}
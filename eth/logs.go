@@ -0,0 +1,32 @@
+package eth
+
+import (
+	"fmt"
+
+	web3types "github.com/alethio/web3-go/types"
+	"github.com/pkg/errors"
+)
+
+// GetLogs returns the logs emitted by the given contracts in the blocks [from, to]; the logs the node marks as removed
+// are skipped
+func GetLogs(from, to int64, addresses []string) ([]web3types.Log, error) {
+	var result []web3types.Log
+
+	err := instance.ethrpc.MakeRequest(&result, "eth_getLogs", map[string]interface{}{
+		"fromBlock": fmt.Sprintf("0x%x", from),
+		"toBlock":   fmt.Sprintf("0x%x", to),
+		"address":   addresses,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get logs of blocks %d-%d", from, to)
+	}
+
+	var logs []web3types.Log
+	for _, l := range result {
+		if !l.Removed {
+			logs = append(logs, l)
+		}
+	}
+
+	return logs, nil
+}
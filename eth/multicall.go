@@ -0,0 +1,246 @@
+package eth
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/alethio/web3-go/etherr"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/ethtypes"
+	"github.com/barnbridge/meminero/utils"
+)
+
+var metricsMulticallSize = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "eth_multicall_size",
+	Help:    "Number of contract calls aggregated in a single multicall",
+	Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200},
+})
+
+// multicallGas is the gas limit of the aggregated calls; the sub-calls share it, so the default limit of single calls
+// would make them run out of gas in big batches (nodes lower it to their own call gas cap, e.g. 50M on geth)
+const multicallGas = "0x2faf080"
+
+type multicallCall struct {
+	Target   common.Address
+	CallData []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+type multicallRequest struct {
+	target string
+	input  string
+
+	result string
+	err    error
+}
+
+type multicallBatch struct {
+	requests []*multicallRequest
+	closed   bool
+	done     chan struct{}
+}
+
+// multicaller collects the contract calls made at the same block and executes them through the Multicall2
+// tryAggregate function, in the same way the httprpc batch loader collects the JSON-RPC requests
+// A batch is sent when it reaches maxCalls or `wait` after its first call, whichever comes first.
+type multicaller struct {
+	address      string
+	startAtBlock int64
+	maxCalls     int
+	wait         time.Duration
+
+	mu      sync.Mutex
+	batches map[int64]*multicallBatch
+
+	logger *logrus.Entry
+}
+
+func newMulticaller(address string, startAtBlock int64, maxCalls int, wait time.Duration) *multicaller {
+	if maxCalls < 1 {
+		maxCalls = 1
+	}
+
+	return &multicaller{
+		address:      utils.NormalizeAddress(address),
+		startAtBlock: startAtBlock,
+		maxCalls:     maxCalls,
+		wait:         wait,
+		batches:      make(map[int64]*multicallBatch),
+		logger:       logrus.WithField("module", "multicall"),
+	}
+}
+
+func (m *multicaller) call(address string, input string, block int64) (string, error) {
+	req := &multicallRequest{target: address, input: input}
+
+	m.mu.Lock()
+	b := m.batches[block]
+	if b == nil {
+		b = &multicallBatch{done: make(chan struct{})}
+		m.batches[block] = b
+
+		go func() {
+			time.Sleep(m.wait)
+			m.flush(block, b)
+		}()
+	}
+
+	b.requests = append(b.requests, req)
+
+	// a full batch is detached right away so the next calls at the same block start a new one instead of piling up on
+	// it until it's flushed
+	full := len(b.requests) >= m.maxCalls
+	if full {
+		delete(m.batches, block)
+	}
+	m.mu.Unlock()
+
+	if full {
+		go m.flush(block, b)
+	}
+
+	<-b.done
+
+	return req.result, req.err
+}
+
+// flush closes the batch so no other call is added to it and executes it; it's a no-op if the batch was already
+// flushed
+func (m *multicaller) flush(block int64, b *multicallBatch) {
+	m.mu.Lock()
+	if b.closed {
+		m.mu.Unlock()
+		return
+	}
+
+	b.closed = true
+	if m.batches[block] == b {
+		delete(m.batches, block)
+	}
+	m.mu.Unlock()
+
+	m.execute(block, b.requests)
+	close(b.done)
+}
+
+func (m *multicaller) execute(block int64, requests []*multicallRequest) {
+	metricsMulticallSize.Observe(float64(len(requests)))
+
+	if len(requests) == 1 {
+		requests[0].result, requests[0].err = CallRawAtBlock(requests[0].target, requests[0].input, block)
+		return
+	}
+
+	results, err := m.tryAggregate(block, requests)
+	if err != nil {
+		// the aggregated call itself failed, so the calls are retried one by one to get their individual results
+		m.logger.WithField("block", block).Warnf("multicall failed, falling back to single calls: %s", err)
+
+		m.callSingle(block, requests)
+
+		return
+	}
+
+	var retries []*multicallRequest
+	for i, r := range requests {
+		res := results[i]
+
+		if !res.Success {
+			// a failed sub-call without a revert reason may also have run out of the gas left to the aggregated call,
+			// so it's only considered reverted if it also fails on its own
+			reason, ok := revertReason(res.ReturnData)
+			if !ok {
+				retries = append(retries, r)
+				continue
+			}
+
+			r.err = errors.Wrap(ErrReverted, reason)
+			continue
+		}
+
+		if len(res.ReturnData) == 0 {
+			r.err = etherr.Empty
+			continue
+		}
+
+		r.result = "0x" + hex.EncodeToString(res.ReturnData)
+	}
+
+	m.callSingle(block, retries)
+}
+
+// callSingle executes the calls one by one, concurrently
+func (m *multicaller) callSingle(block int64, requests []*multicallRequest) {
+	var wg sync.WaitGroup
+	for _, r := range requests {
+		r := r
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			r.result, r.err = CallRawAtBlock(r.target, r.input, block)
+		}()
+	}
+	wg.Wait()
+}
+
+func (m *multicaller) tryAggregate(block int64, requests []*multicallRequest) ([]multicallResult, error) {
+	calls := make([]multicallCall, len(requests))
+	for i, r := range requests {
+		data, err := DecodeString(r.input)
+		if err != nil {
+			return nil, err
+		}
+
+		calls[i] = multicallCall{
+			Target:   common.HexToAddress(r.target),
+			CallData: data,
+		}
+	}
+
+	a := *ethtypes.Multicall2.ABI
+
+	input, err := a.Pack("tryAggregate", false, calls)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate multicall input")
+	}
+
+	output, err := callRawAtBlock(m.address, "0x"+hex.EncodeToString(input), block, multicallGas)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []multicallResult
+	err = DecodeFunctionOutputToInterface(a, "tryAggregate", output, &results)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode multicall output")
+	}
+
+	if len(results) != len(requests) {
+		return nil, errors.Errorf("got %d multicall results for %d calls", len(results), len(requests))
+	}
+
+	return results, nil
+}
+
+// revertReason decodes the revert reason of a failed call, if there is one
+func revertReason(data []byte) (string, bool) {
+	reason, err := abi.UnpackRevert(data)
+	if err != nil || reason == "" {
+		return "", false
+	}
+
+	return reason, true
+}
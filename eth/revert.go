@@ -0,0 +1,31 @@
+package eth
+
+import (
+	"strings"
+
+	"github.com/alethio/web3-go/etherr"
+	"github.com/pkg/errors"
+)
+
+// ErrReverted is the cause of the errors returned by contract calls that reverted
+var ErrReverted = errors.New("execution reverted")
+
+// IsReverted tells whether the contract call failed because it reverted (as opposed to a node or network error)
+func IsReverted(err error) bool {
+	return err != nil && errors.Cause(err) == ErrReverted
+}
+
+// checkReverted converts the node-specific revert errors (geth: "execution reverted", openethereum: "VM execution
+// error. Reverted 0x...") to ErrReverted
+func checkReverted(err error) error {
+	rpcErr, ok := errors.Cause(err).(*etherr.RpcError)
+	if !ok {
+		return err
+	}
+
+	if rpcErr.Code == 3 || strings.Contains(strings.ToLower(rpcErr.Error()), "reverted") {
+		return errors.Wrap(ErrReverted, strings.TrimSpace(rpcErr.Error()))
+	}
+
+	return err
+}
@@ -0,0 +1,33 @@
+package rpcpool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricsRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rpc_endpoint_requests",
+		Help: "Number of JSON-RPC requests sent to each endpoint",
+	}, []string{"endpoint"})
+
+	metricsErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rpc_endpoint_errors",
+		Help: "Number of failed JSON-RPC requests (connection errors, timeouts, throttling) for each endpoint",
+	}, []string{"endpoint"})
+
+	metricsMissing = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rpc_endpoint_missing_data",
+		Help: "Number of JSON-RPC requests that got a null result or a not found error from each endpoint and were retried on the others",
+	}, []string{"endpoint"})
+
+	metricsHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rpc_endpoint_healthy",
+		Help: "Whether the endpoint is used (1) or excluded after failing (0)",
+	}, []string{"endpoint"})
+
+	metricsLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rpc_endpoint_lag",
+		Help: "Number of blocks the endpoint is behind the best endpoint",
+	}, []string{"endpoint"})
+)
@@ -0,0 +1,431 @@
+package rpcpool
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alethio/web3-go/etherr"
+	"github.com/alethio/web3-go/ethrpc/provider/httprpc"
+	"github.com/alethio/web3-go/jsonrpc2"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+var (
+	instance *Pool
+	initMu   sync.Mutex
+)
+
+// Pool is a JSON-RPC provider that spreads the calls across multiple endpoints
+// Every call goes to one of the healthy endpoints, picked randomly according to the weights; if the endpoint fails
+// (connection errors, timeouts, throttling), the call is retried on the next one. Endpoints that fail or fall behind
+// the best block are excluded until they pass a health check.
+// Calls that get a null result or a "not found" error are also retried on the other endpoints, since a healthy
+// endpoint can still be a few blocks behind the one that announced the block; the endpoint isn't excluded for it.
+type Pool struct {
+	endpoints []*endpoint
+	interval  time.Duration
+	maxLag    int64
+
+	stop   chan struct{}
+	logger *logrus.Entry
+}
+
+// Init builds the pool shared by all the JSON-RPC clients from the `eth` config; the rate limits of the endpoints are
+// enforced across the whole process, so every client must use the same pool
+func Init() (*Pool, error) {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	if instance != nil {
+		return instance, nil
+	}
+
+	endpoints := config.Store.ETH.Endpoints
+	if len(endpoints) == 0 {
+		if config.Store.ETH.Config.HTTP == "" {
+			return nil, errors.New("no JSON-RPC endpoint configured")
+		}
+
+		endpoints = []config.Endpoint{{URL: config.Store.ETH.Config.HTTP, Weight: 1}}
+	}
+
+	p := &Pool{
+		interval: config.Store.ETH.HealthCheck.Interval,
+		maxLag:   config.Store.ETH.HealthCheck.MaxLag,
+		stop:     make(chan struct{}),
+		logger:   logrus.WithField("module", "rpcpool"),
+	}
+
+	for _, e := range endpoints {
+		ep, err := newEndpoint(e)
+		if err != nil {
+			return nil, err
+		}
+
+		p.endpoints = append(p.endpoints, ep)
+	}
+
+	err := p.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	instance = p
+
+	return p, nil
+}
+
+// Start runs a first health check and starts checking the endpoints periodically; it fails only if none of the
+// endpoints is reachable
+func (p *Pool) Start() error {
+	p.checkHealth()
+
+	healthy := 0
+	for _, e := range p.endpoints {
+		if e.isHealthy() {
+			healthy++
+		}
+	}
+
+	if healthy == 0 {
+		return errors.New("none of the JSON-RPC endpoints is healthy")
+	}
+
+	p.logger.Infof("%d/%d endpoints healthy", healthy, len(p.endpoints))
+
+	if p.interval > 0 {
+		go p.watch()
+	}
+
+	return nil
+}
+
+// Stop stops the health checks
+func (p *Pool) Stop() {
+	close(p.stop)
+}
+
+// Call calls a RPC method on one of the endpoints, failing over to the others if it's not reachable
+func (p *Pool) Call(result interface{}, method string, params ...interface{}) error {
+	return p.do(func(e *endpoint) error {
+		return e.provider.Call(result, method, params...)
+	})
+}
+
+// CallRaw calls a RPC method on one of the endpoints and returns the raw result, failing over to the others if it's
+// not reachable or doesn't have the data
+func (p *Pool) CallRaw(method string, params ...interface{}) ([]byte, error) {
+	var raw []byte
+
+	err := p.do(func(e *endpoint) error {
+		var err error
+		raw, err = e.provider.CallRaw(method, params...)
+		if err != nil {
+			return err
+		}
+
+		return rawMissingData(raw)
+	})
+
+	// none of the endpoints has the data; the response of the last one is returned as is, like a single provider would
+	if isMissingData(err) {
+		return raw, nil
+	}
+
+	return raw, err
+}
+
+// Subscribe is not available since all the endpoints are http
+func (p *Pool) Subscribe(receiver chan *json.RawMessage, method string, event string, params ...interface{}) error {
+	return errors.New("subscriptions are not supported by the rpc pool")
+}
+
+func (p *Pool) do(call func(e *endpoint) error) error {
+	var err error
+
+	for _, e := range p.pick() {
+		err = e.limiter.Wait(context.Background())
+		if err != nil {
+			return errors.Wrap(err, "could not wait for rate limiter")
+		}
+
+		err = call(e)
+		metricsRequests.WithLabelValues(e.name).Inc()
+
+		if isMissingData(err) {
+			metricsMissing.WithLabelValues(e.name).Inc()
+			continue
+		}
+
+		if !isEndpointError(err) {
+			return err
+		}
+
+		metricsErrors.WithLabelValues(e.name).Inc()
+
+		if e.setHealthy(false) {
+			p.logger.WithField("endpoint", e.name).Warnf("endpoint failed, excluding it until the next health check: %s", err)
+		}
+	}
+
+	return err
+}
+
+// pick returns the endpoints in the order in which they should be tried: the healthy ones in a random order weighted
+// by their weight, followed by the unhealthy ones as a last resort
+func (p *Pool) pick() []*endpoint {
+	var healthy, unhealthy []*endpoint
+	for _, e := range p.endpoints {
+		if e.isHealthy() {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+
+	// weighted random order: every endpoint gets a key of rand^(1/weight) and the highest keys go first
+	keys := make(map[*endpoint]float64, len(healthy))
+	for _, e := range healthy {
+		keys[e] = math.Pow(rand.Float64(), 1/float64(e.weight))
+	}
+
+	sort.Slice(healthy, func(i, j int) bool {
+		return keys[healthy[i]] > keys[healthy[j]]
+	})
+
+	return append(healthy, unhealthy...)
+}
+
+func (p *Pool) watch() {
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			p.checkHealth()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// checkHealth gets the best block from all the endpoints; an endpoint is healthy if it responds and it's not more
+// than maxLag blocks behind the highest one
+func (p *Pool) checkHealth() {
+	heights := make([]int64, len(p.endpoints))
+	errs := make([]error, len(p.endpoints))
+
+	var wg sync.WaitGroup
+	for i, e := range p.endpoints {
+		i, e := i, e
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			heights[i], errs[i] = e.blockNumber()
+		}()
+	}
+	wg.Wait()
+
+	var best int64
+	for i := range p.endpoints {
+		if errs[i] == nil && heights[i] > best {
+			best = heights[i]
+		}
+	}
+
+	for i, e := range p.endpoints {
+		log := p.logger.WithField("endpoint", e.name)
+
+		healthy := true
+		if errs[i] != nil {
+			log.Warnf("health check failed: %s", errs[i])
+			healthy = false
+		} else if best-heights[i] > p.maxLag {
+			log.Warnf("endpoint is %d blocks behind", best-heights[i])
+			healthy = false
+		}
+
+		if errs[i] == nil {
+			metricsLag.WithLabelValues(e.name).Set(float64(best - heights[i]))
+		}
+
+		if e.setHealthy(healthy) && healthy {
+			log.Info("endpoint is healthy again")
+		}
+	}
+}
+
+// isEndpointError tells whether an error was caused by the endpoint rather than by the request itself (e.g. a
+// reverted call); only the former are retried on other endpoints
+func isEndpointError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if rpcErr, ok := errors.Cause(err).(*etherr.RpcError); ok {
+		// -32005 is the "limit exceeded" code used by most public nodes; some of them use the http status code
+		return rpcErr.Code == -32005 || rpcErr.Code == 429
+	}
+
+	return true
+}
+
+// isMissingData tells whether the endpoint answered without the requested data: a null result or a "not found" error
+// (e.g. "header not found", "unknown block"), which is what a lagging endpoint returns for the newest blocks
+func isMissingData(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	rpcErr, ok := errors.Cause(err).(*etherr.RpcError)
+	if !ok {
+		return false
+	}
+
+	if rpcErr == etherr.Nil {
+		return true
+	}
+
+	msg := strings.ToLower(rpcErr.Error())
+
+	return strings.Contains(msg, "not found") || strings.Contains(msg, "unknown block")
+}
+
+// rawMissingData returns the error the provider's Call would return for a raw response that doesn't have the data
+func rawMissingData(raw []byte) error {
+	resp, err := jsonrpc2.DecodeResponse(raw)
+	if err != nil {
+		// batched responses and malformed ones are left to the caller
+		return nil
+	}
+
+	if resp.Error != nil {
+		err := etherr.New(resp.Error.Message, resp.Error.Code, resp.Error.Data)
+		if isMissingData(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	if string(resp.Result) == "null" {
+		return etherr.Nil
+	}
+
+	return nil
+}
+
+type endpoint struct {
+	name     string
+	weight   int
+	provider *httprpc.HTTPProvider
+	limiter  *rate.Limiter
+
+	mu      sync.RWMutex
+	healthy bool
+}
+
+func newEndpoint(c config.Endpoint) (*endpoint, error) {
+	batchLoader, err := httprpc.NewBatchLoader(config.Store.ETH.MaxBatch, 4*time.Millisecond)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not init batch loader")
+	}
+
+	provider, err := httprpc.NewWithLoader(c.URL, batchLoader)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not init httprpc provider")
+	}
+	if config.Store.ETH.Timeout > 0 {
+		provider.SetHTTPTimeout(config.Store.ETH.Timeout)
+	}
+
+	// urls of hosted nodes usually contain api keys, so only the host is used in logs and metrics
+	name := c.Name
+	if name == "" {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid endpoint url")
+		}
+
+		name = u.Host
+	}
+
+	weight := c.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if c.RateLimit > 0 {
+		burst := c.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+
+		limiter = rate.NewLimiter(rate.Limit(c.RateLimit), burst)
+	}
+
+	return &endpoint{
+		name:     name,
+		weight:   weight,
+		provider: provider,
+		limiter:  limiter,
+	}, nil
+}
+
+func (e *endpoint) blockNumber() (int64, error) {
+	err := e.limiter.Wait(context.Background())
+	if err != nil {
+		return 0, err
+	}
+
+	var result string
+
+	err = e.provider.Call(&result, "eth_blockNumber")
+	metricsRequests.WithLabelValues(e.name).Inc()
+	if err != nil {
+		metricsErrors.WithLabelValues(e.name).Inc()
+		return 0, err
+	}
+
+	return strconv.ParseInt(result, 0, 64)
+}
+
+func (e *endpoint) isHealthy() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.healthy
+}
+
+// setHealthy updates the state of the endpoint and returns true if it changed
+func (e *endpoint) setHealthy(healthy bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	changed := e.healthy != healthy
+	e.healthy = healthy
+
+	if healthy {
+		metricsHealthy.WithLabelValues(e.name).Set(1)
+	} else {
+		metricsHealthy.WithLabelValues(e.name).Set(0)
+	}
+
+	return changed
+}
@@ -2,23 +2,32 @@ package glue
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
+	web3types "github.com/alethio/web3-go/types"
 	"github.com/alethio/web3-go/validator"
-	"github.com/barnbridge/meminero/config"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lacasian/ethwheels/bestblock"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/sirupsen/logrus"
 
+	"github.com/barnbridge/meminero/config"
 	"github.com/barnbridge/meminero/processor"
+	"github.com/barnbridge/meminero/processor/storables/contractevents"
+	"github.com/barnbridge/meminero/rawstore"
 	"github.com/barnbridge/meminero/scraper"
 	"github.com/barnbridge/meminero/state"
 	"github.com/barnbridge/meminero/types"
+	"github.com/barnbridge/meminero/utils"
 )
 
+// abortTimeout is how long an aborted block has to stop after the shutdown deadline
+const abortTimeout = 5 * time.Second
+
 var (
 	metricsBlocksProcessed = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "scraper_processed_blocks",
@@ -32,6 +41,15 @@ var (
 		Name: "scraper_errored_blocks",
 		Help: "Number of blocks errored and re-queued",
 	})
+	metricsBlocksDeadLettered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "scraper_dead_lettered_blocks",
+		Help: "Number of blocks moved to the dead-letter queue after failing too many times",
+	})
+	metricsBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "scraper_batch_size",
+		Help:    "Number of blocks saved in a single database transaction",
+		Buckets: []float64{2, 5, 10, 20, 50, 100},
+	})
 	metricsScrapingDuration = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "scraper_scrape_duration_ms",
 		Help:    "How long did it take to scrape the data from the node",
@@ -42,60 +60,104 @@ var (
 		Help:    "How long did it take to process the data",
 		Buckets: []float64{1, 10, 50, 100, 500, 1000, 2000, 4000},
 	})
+	metricsRawStoreReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_raw_store_reads",
+		Help: "Number of blocks looked up in the raw store, by result (hit or miss)",
+	}, []string{"result"})
 )
 
 type Glue struct {
 	state   *state.Manager
 	scraper *scraper.Scraper
+	tracker *bestblock.Tracker
 	db      *pgxpool.Pool
 	logger  *logrus.Entry
 
-	stopMu sync.Mutex
+	// raw keeps the raw data of the scraped blocks; it's nil if the raw store is disabled
+	raw rawstore.Store
+
+	// work is the context of the blocks being processed; it outlives the context given to Run, so the block in
+	// flight can be finished during shutdown, and it's only cancelled if that takes longer than the shutdown deadline
+	work  context.Context
+	abort context.CancelFunc
+	done  chan struct{}
 }
 
-func New(db *pgxpool.Pool, state *state.Manager) (*Glue, error) {
+// New creates a new Glue; the tracker is optional and only used to decide when to switch to batched processing
+func New(db *pgxpool.Pool, tracker *bestblock.Tracker, state *state.Manager) (*Glue, error) {
 	logger := logrus.WithField("module", "glue")
 
+	// fail early instead of on the first block if the storables don't fit together
+	_, err := processor.StorableGraph()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid storable graph")
+	}
+
+	if config.Store.Storable.ContractEvents.Enabled {
+		err = contractevents.Init(context.Background(), db)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not init contract events")
+		}
+	}
+
 	s, err := scraper.New()
 	if err != nil {
 		return nil, errors.Wrap(err, "could not init scraper")
 	}
 
+	err = rawstore.CheckConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw rawstore.Store
+	if config.Store.RawStore.Enabled {
+		raw, err = rawstore.New(db)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not init raw store")
+		}
+	}
+
+	work, abort := context.WithCancel(context.Background())
+
 	return &Glue{
 		state:   state,
 		scraper: s,
+		tracker: tracker,
 		db:      db,
 		logger:  logger,
+		raw:     raw,
+		work:    work,
+		abort:   abort,
+		done:    make(chan struct{}),
 	}, nil
 }
 
-func (g *Glue) ScrapeSingleBlock(ctx context.Context, b int64) (bool,error) {
+func (g *Glue) ScrapeSingleBlock(ctx context.Context, b int64) (bool, error) {
 	log := g.logger.WithField("block", b)
 	log.Info("processing block")
 
-	start := time.Now()
-	blk, err := g.scraper.Exec(b)
+	// the cache is refreshed before scraping because the log filter is built from it
+	log.Debug("updating state cache")
+	err := g.state.RefreshCache(ctx)
 	if err != nil {
-		return false, errors.Wrap(err, "could not scrape block")
+		if ctx.Err() != nil {
+			return false, errors.Wrap(err, "could not update state cache")
+		}
+
+		log.Fatal(err)
 	}
 
-	_, err = g.validateBlock(log, blk)
+	start := time.Now()
+	blk, err := g.scrapeBlock(ctx, log, b)
 	if err != nil {
-		return false, errors.Wrap(err, "could not validate block")
+		return false, err
 	}
 
-	log.Debug("block is valid; processing")
-
 	metricsScrapingDuration.Observe(float64(time.Since(start) / time.Millisecond))
 
 	startProcessing := time.Now()
 
-	log.Debug("updating state cache")
-	err = g.state.RefreshCache(ctx)
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	p, err := processor.New(blk, g.state)
 	if err != nil {
 		return false, errors.Wrap(err, "could not init processor")
@@ -112,58 +174,404 @@ func (g *Glue) ScrapeSingleBlock(ctx context.Context, b int64) (bool,error) {
 	return savedBlock, nil
 }
 
+// ScrapeBatch processes a window of consecutive blocks and saves all of them in a single database transaction
+// the results are reported per block, indexed the same as blocks
+func (g *Glue) ScrapeBatch(ctx context.Context, blocks []int64) ([]bool, []error) {
+	log := g.logger.WithField("blocks", fmt.Sprintf("%d-%d", blocks[0], blocks[len(blocks)-1]))
+	log.Info("processing batch")
+
+	saved := make([]bool, len(blocks))
+	errs := make([]error, len(blocks))
+
+	log.Debug("updating state cache")
+	err := g.state.RefreshCache(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = errors.Wrap(err, "could not update state cache")
+			for i := range errs {
+				errs[i] = err
+			}
+
+			return saved, errs
+		}
+
+		log.Fatal(err)
+	}
+
+	start := time.Now()
+
+	var raw []*types.RawData
+	// rest are the blocks of the window that are processed in a new batch after this one
+	var rest []int64
+	if config.Store.Feature.LogScraping {
+		filter := g.state.LogFilter()
+
+		raw, err = g.scraper.ExecLogsRange(blocks[0], blocks[len(blocks)-1], filter)
+		if err != nil {
+			err = errors.Wrap(err, "could not scrape batch")
+			for i := range errs {
+				errs[i] = err
+			}
+
+			return saved, errs
+		}
+
+		// a factory event can add new contracts to the filter, so the blocks that follow it have to be scraped again
+		// with the updated filter; the simplest way to do that is to process the window block by block
+		if discoveryBlock(raw[:len(raw)-1], filter) >= 0 {
+			log.Info("found events that change the log filter; processing blocks one by one")
+
+			for i, b := range blocks {
+				saved[i], errs[i] = g.ScrapeSingleBlock(ctx, b)
+			}
+
+			return saved, errs
+		}
+	} else {
+		raw = make([]*types.RawData, len(blocks))
+
+		var wg sync.WaitGroup
+		for i, b := range blocks {
+			i, b := i, b
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				raw[i], errs[i] = g.scrapeBlock(ctx, log.WithField("block", b), b)
+			}()
+		}
+		wg.Wait()
+
+		// the blocks are complete, but the contracts added by a factory event are only in the state cache once the
+		// block is saved; the window is cut after that block and the blocks that follow it make up a new batch
+		if i := discoveryBlock(raw[:len(raw)-1], g.state.LogFilter()); i >= 0 {
+			log.WithField("block", blocks[i]).Info("found events that change the monitored contracts; splitting batch")
+
+			rest = blocks[i+1:]
+			blocks, raw = blocks[:i+1], raw[:i+1]
+		}
+	}
+
+	metricsScrapingDuration.Observe(float64(time.Since(start) / time.Millisecond))
+
+	startProcessing := time.Now()
+
+	var processors []*processor.Processor
+	var indexes []int
+	for i, blk := range raw {
+		if errs[i] != nil {
+			continue
+		}
+
+		p, err := processor.New(blk, g.state)
+		if err != nil {
+			errs[i] = errors.Wrap(err, "could not init processor")
+			continue
+		}
+
+		processors = append(processors, p)
+		indexes = append(indexes, i)
+	}
+
+	batchSaved, batchErrs := processor.StoreBatch(ctx, g.db, processors)
+	for j, i := range indexes {
+		saved[i] = batchSaved[j]
+		if batchErrs[j] != nil {
+			errs[i] = errors.Wrap(batchErrs[j], "could not store block")
+		}
+	}
+
+	metricsBatchSize.Observe(float64(len(processors)))
+	metricsProcessingDuration.Observe(float64(time.Since(startProcessing) / time.Millisecond))
+	log.WithField("duration", time.Since(start)).Info("done processing batch")
+
+	if len(rest) > 0 {
+		last := len(blocks) - 1
+		if errs[last] != nil {
+			err := errors.Errorf("not processed because block %d, which changes the monitored contracts, failed", blocks[last])
+			for i := range rest {
+				errs[len(blocks)+i] = err
+			}
+
+			return saved, errs
+		}
+
+		restSaved, restErrs := g.ScrapeBatch(ctx, rest)
+		copy(saved[len(blocks):], restSaved)
+		copy(errs[len(blocks):], restErrs)
+	}
+
+	return saved, errs
+}
+
+func (g *Glue) scrapeBlock(ctx context.Context, log *logrus.Entry, b int64) (*types.RawData, error) {
+	if config.Store.Feature.LogScraping {
+		// the logs are fetched by block hash, so they always match the header; there's nothing else to validate
+		blk, err := g.scraper.ExecLogs(b, g.state.LogFilter())
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scrape block logs")
+		}
+
+		return blk, nil
+	}
+
+	if g.raw != nil {
+		blk, err := g.storedBlock(ctx, b)
+		if err != nil {
+			// fall back to the node
+			log.Errorf("could not read raw store: %s", err)
+		} else if blk != nil {
+			log.Debug("using block from raw store")
+			return blk, nil
+		}
+	}
+
+	blk, err := g.scraper.Exec(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not scrape block")
+	}
+
+	_, err = g.validateBlock(log, blk)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not validate block")
+	}
+
+	log.Debug("block is valid; processing")
+
+	if g.raw != nil {
+		err = g.raw.Save(ctx, blk)
+		if err != nil {
+			log.Errorf("could not save block to raw store: %s", err)
+		}
+	}
+
+	return blk, nil
+}
+
+// storedBlock returns the raw data of the block from the raw store if it has the version that is currently on the
+// canonical chain; only the header is fetched from the node to find out which one that is
+func (g *Glue) storedBlock(ctx context.Context, b int64) (*types.RawData, error) {
+	header, err := g.scraper.Header(b)
+	if err != nil {
+		return nil, err
+	}
+
+	blk, err := g.raw.Get(ctx, b, header.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if blk == nil {
+		metricsRawStoreReads.WithLabelValues("miss").Inc()
+		return nil, nil
+	}
+
+	metricsRawStoreReads.WithLabelValues("hit").Inc()
+
+	return blk, nil
+}
+
+// Run takes tasks from the queue and processes them until ctx is cancelled; the block in flight at that moment is
+// still finished (see Stop)
 func (g *Glue) Run(ctx context.Context) {
+	defer close(g.done)
+
 	for {
-		b, err := g.state.NextTask(ctx)
+		task, err := g.state.NextTask(ctx)
 		if err != nil && err != context.Canceled {
 			g.logger.Fatal(err)
 		} else if err == context.Canceled {
 			return
 		}
 
-		acquired, err := g.state.LockBlock(b)
+		b := task.Block
+
+		if ctx.Err() != nil {
+			// the task was taken right when the shutdown started
+			g.requeue(task)
+			return
+		}
+
+		lease, err := g.state.LockBlock(g.work, b)
 		if err != nil {
 			g.logger.Fatal(err)
 		}
-		if !acquired {
+		if lease == nil {
 			// could not get lock, already being worked on, skip
 			continue
 		}
 
-		g.stopMu.Lock()
+		if g.shouldBatch(b) {
+			// the leases of the window are derived from the first one, so losing it aborts the whole batch
+			leases := append([]*state.Lease{lease}, g.claimWindow(lease.Context(), task)...)
 
-		savedBlock, err := g.ScrapeSingleBlock(ctx, b)
-		if err != nil {
-			g.logger.Error(err)
-			err = g.state.UnlockBlock(b)
-			if err != nil {
-				g.logger.Fatal(err)
+			blocks := make([]int64, len(leases))
+			for i, l := range leases {
+				blocks[i] = l.Block
 			}
-			if config.Store.Feature.RequeueFailedBlocks {
-				g.mustRequeueTask(b)
+
+			saved, errs := g.ScrapeBatch(state.WithFences(lease.Context(), leases...), blocks)
+			for i, l := range leases {
+				g.finishBlock(state.Task{Block: l.Block, Lane: task.Lane}, l, saved[i], errs[i])
 			}
-			metricsBlocksErrored.Inc()
 		} else {
-			err = g.state.UnlockBlock(b)
-			if err != nil {
-				g.logger.Fatal(err)
-			}
-			if savedBlock {
-				metricsBlocksProcessed.Inc()
-			} else {
-				metricsBlocksSkipped.Inc()
-			}
+			savedBlock, err := g.ScrapeSingleBlock(state.WithFences(lease.Context(), lease), b)
+			g.finishBlock(task, lease, savedBlock, err)
 		}
+	}
+}
+
+// Stop waits for Run to finish the block in flight after its context was cancelled; if that takes longer than the
+// timeout, the block is aborted (its database transaction is rolled back) and put back in the queue
+func (g *Glue) Stop(timeout time.Duration) {
+	select {
+	case <-g.done:
+		return
+	case <-time.After(timeout):
+	}
+
+	g.logger.Warn("shutdown deadline reached; aborting the block in flight")
+	g.abort()
 
-		g.stopMu.Unlock()
+	select {
+	case <-g.done:
+	case <-time.After(abortTimeout):
+		g.logger.Error("the block in flight did not stop after being aborted")
 	}
 }
 
-func (g *Glue) mustRequeueTask(b int64) {
-	err := g.state.AddTaskToQueue(b)
+// requeue puts a task that was taken from the queue but not finished back in its lane, without counting it as a
+// failed attempt
+func (g *Glue) requeue(task state.Task) {
+	g.logger.WithField("block", task.Block).Info("putting unfinished block back in the queue")
+
+	err := g.state.AddTaskToQueue(task.Lane, task.Block)
+	if err != nil {
+		g.logger.WithField("block", task.Block).Errorf("could not requeue unfinished block: %s", err)
+	}
+}
+
+func (g *Glue) finishBlock(task state.Task, lease *state.Lease, savedBlock bool, blockErr error) {
+	b := task.Block
+
+	err := lease.Release()
 	if err != nil {
 		g.logger.Fatal(err)
 	}
+
+	if blockErr != nil && (lease.Lost() || errors.Cause(blockErr) == state.ErrLockLost) {
+		// another worker owns the block now, so it's up to that one to finish or retry it
+		g.logger.WithField("block", b).Warnf("lost the block lock; leaving it to the worker that took over: %s", blockErr)
+		metricsBlocksErrored.Inc()
+
+		return
+	}
+
+	if blockErr != nil && g.work.Err() != nil {
+		g.logger.WithField("block", b).Warnf("block aborted during shutdown: %s", blockErr)
+		g.requeue(task)
+
+		return
+	}
+
+	if blockErr != nil {
+		g.logger.WithField("block", b).Error(blockErr)
+		if config.Store.Feature.RequeueFailedBlocks {
+			g.mustRetryTask(task, blockErr)
+		}
+		metricsBlocksErrored.Inc()
+
+		return
+	}
+
+	if config.Store.Feature.RequeueFailedBlocks {
+		err = g.state.ClearTaskFailure(b)
+		if err != nil {
+			g.logger.Fatal(err)
+		}
+	}
+
+	if savedBlock {
+		metricsBlocksProcessed.Inc()
+	} else {
+		metricsBlocksSkipped.Inc()
+	}
+}
+
+// shouldBatch returns true if batching is enabled and the block is far enough behind the best block
+func (g *Glue) shouldBatch(b int64) bool {
+	if !config.Store.Feature.Batch.Enabled || config.Store.Feature.Batch.Size < 2 || g.tracker == nil {
+		return false
+	}
+
+	return g.tracker.BestBlock()-b > config.Store.Feature.Batch.Threshold
+}
+
+// claimWindow takes the blocks that directly follow the task's block out of its lane and locks them, so they can be
+// processed in the same batch; it stops at the first block that is not queued in the lane or that is already being
+// worked on
+func (g *Glue) claimWindow(ctx context.Context, task state.Task) []*state.Lease {
+	var window []*state.Lease
+
+	for next := task.Block + 1; int64(len(window))+1 < config.Store.Feature.Batch.Size; next++ {
+		removed, err := g.state.RemoveTask(task.Lane, next)
+		if err != nil {
+			g.logger.Fatal(err)
+		}
+		if !removed {
+			break
+		}
+
+		lease, err := g.state.LockBlock(ctx, next)
+		if err != nil {
+			g.logger.Fatal(err)
+		}
+		if lease == nil {
+			// same as in Run: the block is already being worked on
+			break
+		}
+
+		window = append(window, lease)
+	}
+
+	return window
+}
+
+// mustRetryTask queues a failed block again with a backoff, or moves it to the dead-letter queue if it failed too many
+// times, so a block that keeps failing does not starve the ones after it
+func (g *Glue) mustRetryTask(task state.Task, blockErr error) {
+	dead, err := g.state.RetryTask(task, blockErr)
+	if err != nil {
+		g.logger.Fatal(err)
+	}
+
+	if dead {
+		metricsBlocksDeadLettered.Inc()
+	}
+}
+
+// discoveryBlock returns the index of the first block with logs of the factories of the filter, or -1 if there's none
+func discoveryBlock(blocks []*types.RawData, filter types.LogFilter) int {
+	for i, b := range blocks {
+		if b == nil {
+			continue
+		}
+
+		// the logs are in the receipts when the block was scraped in full
+		logs := append([]web3types.Log{}, b.Logs...)
+		for _, r := range b.Receipts {
+			logs = append(logs, r.Logs...)
+		}
+
+		for _, l := range logs {
+			if filter.IsDiscovery(utils.NormalizeAddress(l.Address)) {
+				return i
+			}
+		}
+	}
+
+	return -1
 }
 
 func (g *Glue) validateBlock(log *logrus.Entry, blk *types.RawData) (bool, error) {
@@ -175,8 +583,3 @@ func (g *Glue) validateBlock(log *logrus.Entry, blk *types.RawData) (bool, error
 
 	return v.Run()
 }
-
-func (g *Glue) Close() {
-	g.stopMu.Lock()
-	defer g.stopMu.Unlock()
-}
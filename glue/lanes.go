@@ -0,0 +1,63 @@
+package glue
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// laneStatsInterval is how often the queue lanes metrics are refreshed
+const laneStatsInterval = 15 * time.Second
+
+var (
+	metricsLaneDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "scraper_queue_lane_depth",
+		Help: "Number of blocks ready to be processed in each lane of the queue",
+	}, []string{"lane"})
+	metricsLaneAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "scraper_queue_lane_age_blocks",
+		Help: "How many blocks behind the best block the oldest block of each lane of the queue is",
+	}, []string{"lane"})
+)
+
+// ReportQueue periodically updates the per-lane metrics of the queue until the context is cancelled
+func (g *Glue) ReportQueue(ctx context.Context) {
+	t := time.NewTicker(laneStatsInterval)
+	defer t.Stop()
+
+	for {
+		g.reportQueue()
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (g *Glue) reportQueue() {
+	stats, err := g.state.QueueStats()
+	if err != nil {
+		g.logger.Errorf("could not get queue stats: %s", err)
+		return
+	}
+
+	var best int64
+	if g.tracker != nil {
+		best = g.tracker.BestBlock()
+	}
+
+	for _, s := range stats {
+		metricsLaneDepth.WithLabelValues(string(s.Lane)).Set(float64(s.Depth))
+
+		var age int64
+		if s.Depth > 0 && best > s.Oldest {
+			age = best - s.Oldest
+		}
+
+		metricsLaneAge.WithLabelValues(string(s.Lane)).Set(float64(age))
+	}
+}
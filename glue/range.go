@@ -0,0 +1,192 @@
+package glue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+const progressInterval = 10 * time.Second
+
+// ScrapeRange processes all the blocks in [from, to] using a pool of `workers` goroutines without going through the
+// task queue; if resume is true, the blocks that are already stored in the database are skipped
+// it returns the list of blocks that failed to be processed
+func (g *Glue) ScrapeRange(ctx context.Context, from, to int64, workers int, resume bool) ([]int64, error) {
+	if from > to {
+		return nil, errors.Errorf("invalid range %d-%d", from, to)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	var stored map[int64]bool
+	if resume {
+		var err error
+		stored, err = g.storedBlocks(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for from <= to && stored[from] {
+			from++
+		}
+
+		if from > to {
+			g.logger.Info("all the blocks in the range are already stored")
+			return nil, nil
+		}
+
+		g.logger.WithField("block", from).Info("resuming from the first block that is not stored")
+	}
+
+	total := to - from + 1
+	for b := range stored {
+		if b >= from {
+			total--
+		}
+	}
+
+	var (
+		processed int64
+		failedMu  sync.Mutex
+		failed    []int64
+	)
+
+	// when batching is enabled, every worker gets windows of consecutive blocks since the whole range is catch-up work
+	windowSize := int64(1)
+	if config.Store.Feature.Batch.Enabled && config.Store.Feature.Batch.Size > 1 {
+		windowSize = config.Store.Feature.Batch.Size
+	}
+
+	windows := make(chan []int64)
+	go func() {
+		defer close(windows)
+
+		var window []int64
+		for b := from; b <= to; b++ {
+			if !stored[b] {
+				window = append(window, b)
+			}
+
+			// a stored block breaks the sequence, so the window is sent as it is
+			if len(window) > 0 && (int64(len(window)) == windowSize || b == to || stored[b+1]) {
+				select {
+				case windows <- window:
+				case <-ctx.Done():
+					return
+				}
+
+				window = nil
+			}
+		}
+	}()
+
+	progressCtx, stopProgress := context.WithCancel(ctx)
+	defer stopProgress()
+	go g.logProgress(progressCtx, &processed, total)
+
+	report := func(b int64, savedBlock bool, err error) {
+		if err != nil {
+			g.logger.WithField("block", b).Error(err)
+
+			failedMu.Lock()
+			failed = append(failed, b)
+			failedMu.Unlock()
+
+			metricsBlocksErrored.Inc()
+		} else if savedBlock {
+			metricsBlocksProcessed.Inc()
+		} else {
+			metricsBlocksSkipped.Inc()
+		}
+
+		atomic.AddInt64(&processed, 1)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for window := range windows {
+				// the in-flight blocks are allowed to finish even if ctx is cancelled so they are not left half-processed
+				if len(window) == 1 {
+					savedBlock, err := g.ScrapeSingleBlock(context.Background(), window[0])
+					report(window[0], savedBlock, err)
+
+					continue
+				}
+
+				saved, errs := g.ScrapeBatch(context.Background(), window)
+				for j, b := range window {
+					report(b, saved[j], errs[j])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return failed, ctx.Err()
+	}
+
+	return failed, nil
+}
+
+// storedBlocks returns the set of blocks in [from, to] that already exist in the database
+func (g *Glue) storedBlocks(ctx context.Context, from, to int64) (map[int64]bool, error) {
+	rows, err := g.db.Query(ctx, `select number from blocks where number between $1 and $2`, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query stored blocks")
+	}
+	defer rows.Close()
+
+	stored := make(map[int64]bool)
+	for rows.Next() {
+		var b int64
+
+		err := rows.Scan(&b)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan stored block")
+		}
+
+		stored[b] = true
+	}
+
+	return stored, rows.Err()
+}
+
+func (g *Glue) logProgress(ctx context.Context, processed *int64, total int64) {
+	start := time.Now()
+
+	t := time.NewTicker(progressInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			done := atomic.LoadInt64(processed)
+			elapsed := time.Since(start)
+
+			log := g.logger.WithField("progress", fmt.Sprintf("%d/%d (%.2f%%)", done, total, float64(done)/float64(total)*100))
+			if done > 0 {
+				rate := float64(done) / elapsed.Seconds()
+				eta := time.Duration(float64(total-done) / rate * float64(time.Second))
+
+				log = log.WithField("blocks/s", fmt.Sprintf("%.2f", rate)).WithField("eta", eta.Round(time.Second))
+			}
+
+			log.Info("range progress")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
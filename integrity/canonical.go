@@ -0,0 +1,77 @@
+package integrity
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/eth"
+)
+
+var metricsForks = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "scraper_integrity_stored_forks",
+	Help: "Number of times a range of stored blocks was found on a fork instead of the canonical chain",
+})
+
+// checkCanonicalChain compares the stored blocks in (start, end] with the canonical chain of the node and returns the
+// first block that is not part of it (0 if all of them are)
+// The stored blocks must form an unbroken hash chain, so they are all canonical if the highest one is; otherwise the
+// fork is found with a binary search. Processing the first orphaned block again rolls back the whole fork through
+// the reorg handling.
+func (c *Checker) checkCanonicalChain(ctx context.Context, start, end int64) (int64, error) {
+	if end <= start {
+		return 0, nil
+	}
+
+	ok, err := c.isCanonical(ctx, end)
+	if err != nil || ok {
+		return 0, err
+	}
+
+	// start is assumed to be canonical (it's the previous checkpoint); if it's not, the reorg handling walks further back
+	low, high := start, end
+	for high-low > 1 {
+		mid := low + (high-low)/2
+
+		ok, err := c.isCanonical(ctx, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	metricsForks.Inc()
+	c.logger.WithFields(logrus.Fields{
+		"first-orphan": high,
+		"highest":      end,
+	}).Warn("stored blocks are not part of the canonical chain")
+
+	return high, nil
+}
+
+func (c *Checker) isCanonical(ctx context.Context, number int64) (bool, error) {
+	var stored string
+	err := c.db.QueryRow(ctx, `select block_hash from blocks where number = $1`, number).Scan(&stored)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "could not get stored block")
+	}
+
+	hash, _, err := eth.GetBlockHashes(number)
+	if err != nil {
+		return false, errors.Wrap(err, "could not get canonical block")
+	}
+
+	return hash == stored, nil
+}
@@ -0,0 +1,99 @@
+package integrity
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/barnbridge/meminero/eth"
+	"github.com/barnbridge/meminero/state"
+)
+
+// confirmBatchSize is the maximum number of blocks confirmed in a single run
+const confirmBatchSize = 1000
+
+var metricsUnconfirmedBlocks = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "scraper_unconfirmed_blocks",
+	Help: "Number of blocks stored before they were final and not confirmed yet (dual finality mode)",
+})
+
+// confirmBlocks marks the unconfirmed blocks that became final as confirmed, after checking they are still part of
+// the canonical chain; the ones that are not are queued again, which rolls them back through the reorg handling
+func (c *Checker) confirmBlocks(ctx context.Context, finalized int64) error {
+	rows, err := c.db.Query(ctx, `
+		select number, block_hash from blocks where not confirmed and number <= $1 order by number limit $2
+	`, finalized, confirmBatchSize)
+	if err != nil {
+		return errors.Wrap(err, "could not get unconfirmed blocks")
+	}
+
+	stored := make(map[int64]string)
+	var numbers []int64
+	for rows.Next() {
+		var n int64
+		var hash string
+
+		err := rows.Scan(&n, &hash)
+		if err != nil {
+			rows.Close()
+			return errors.Wrap(err, "could not scan unconfirmed block")
+		}
+
+		stored[n] = hash
+		numbers = append(numbers, n)
+	}
+	rows.Close()
+
+	if rows.Err() != nil {
+		return errors.Wrap(rows.Err(), "could not get unconfirmed blocks")
+	}
+
+	var confirmed []int64
+	var hashes []string
+	for _, n := range numbers {
+		hash, _, err := eth.GetBlockHashes(n)
+		if err != nil {
+			return errors.Wrap(err, "could not get canonical block")
+		}
+
+		if hash == stored[n] {
+			confirmed = append(confirmed, n)
+			hashes = append(hashes, hash)
+			continue
+		}
+
+		c.logger.WithField("block", n).Warn("unconfirmed block was reorged; queueing replacement")
+
+		err = c.tm.AddTaskToQueue(state.LaneHead, n)
+		if err != nil {
+			return errors.Wrap(err, "could not queue replacement block")
+		}
+	}
+
+	if len(confirmed) > 0 {
+		// matched on the hash as well, in case a block was replaced in the meantime
+		_, err = c.db.Exec(ctx, `
+			update blocks b
+			set confirmed = true
+			from unnest($1::bigint[], $2::text[]) as c(number, block_hash)
+			where b.number = c.number and b.block_hash = c.block_hash
+		`, confirmed, hashes)
+		if err != nil {
+			return errors.Wrap(err, "could not confirm blocks")
+		}
+
+		c.logger.WithField("count", len(confirmed)).Info("confirmed finalized blocks")
+	}
+
+	var unconfirmed int64
+	err = c.db.QueryRow(ctx, `select count(*) from blocks where not confirmed`).Scan(&unconfirmed)
+	if err != nil {
+		return errors.Wrap(err, "could not count unconfirmed blocks")
+	}
+
+	metricsUnconfirmedBlocks.Set(float64(unconfirmed))
+
+	return nil
+}
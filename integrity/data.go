@@ -0,0 +1,384 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/eth"
+	"github.com/barnbridge/meminero/ethtypes"
+	"github.com/barnbridge/meminero/processor"
+	"github.com/barnbridge/meminero/utils"
+)
+
+var metricsDataDiscrepancies = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "scraper_integrity_data_discrepancies",
+	Help: "Number of blocks whose stored events did not match the logs on chain",
+}, []string{"check"})
+
+// dataCheck describes the events a storable keeps for the contracts it tracks
+type dataCheck struct {
+	// name is also the name of the storable the contracts are taken from (see state.EventSourceAddresses)
+	name    string
+	enabled bool
+
+	// events are the topics of the logs that are always stored; the other logs of the contracts are ignored, except for
+	// making sure every stored row points to an existing log
+	events []common.Hash
+	// topics is the number of topics of the stored logs (0 for any)
+	topics int
+
+	// tables hold the stored logs, identified by (tx_hash, log_index)
+	tables []string
+}
+
+type discrepancy struct {
+	block      int64
+	check      string
+	chainLogs  int
+	storedLogs int
+	missing    []string
+	unexpected []string
+}
+
+func dataChecks() []dataCheck {
+	s := config.Store.Storable
+
+	return []dataCheck{
+		{
+			name:    "barn",
+			enabled: s.Barn.Enabled,
+			events: []common.Hash{
+				ethtypes.Barn.DelegateEventID(),
+				ethtypes.Barn.DelegatedPowerIncreasedEventID(),
+				ethtypes.Barn.DelegatedPowerDecreasedEventID(),
+				ethtypes.Barn.LockEventID(),
+				ethtypes.Barn.DepositEventID(),
+				ethtypes.Barn.WithdrawEventID(),
+			},
+			tables: []string{"governance.barn_delegate_actions", "governance.barn_delegate_changes", "governance.barn_locks", "governance.barn_staking_actions"},
+		},
+		{
+			name:    "governance",
+			enabled: s.Governance.Enabled,
+			events: []common.Hash{
+				ethtypes.Governance.ProposalCreatedEventID(),
+				ethtypes.Governance.ProposalQueuedEventID(),
+				ethtypes.Governance.ProposalExecutedEventID(),
+				ethtypes.Governance.ProposalCanceledEventID(),
+				ethtypes.Governance.VoteEventID(),
+				ethtypes.Governance.VoteCanceledEventID(),
+				ethtypes.Governance.AbrogationProposalStartedEventID(),
+				ethtypes.Governance.AbrogationProposalVoteEventID(),
+				ethtypes.Governance.AbrogationProposalVoteCancelledEventID(),
+			},
+			tables: []string{"governance.proposal_events", "governance.votes", "governance.votes_canceled", "governance.abrogation_proposals", "governance.abrogation_votes", "governance.abrogation_votes_canceled"},
+		},
+		{
+			name:    "yieldFarming",
+			enabled: s.YieldFarming.Enabled,
+			events: []common.Hash{
+				ethtypes.YieldFarming.DepositEventID(),
+				ethtypes.YieldFarming.WithdrawEventID(),
+			},
+			tables: []string{"yield_farming.transactions"},
+		},
+		{
+			name:    "erc20Transfers",
+			enabled: s.Erc20Transfers.Enabled,
+			events:  []common.Hash{ethtypes.ERC20.TransferEventID()},
+			// erc721 transfers have the same topic, but the token id is indexed as well
+			topics: 3,
+			tables: []string{"public.erc20_transfers"},
+		},
+		{
+			name:    "smartYield",
+			enabled: s.SmartYield.Enabled,
+			// the junior token transfers are left out; only some of them end up in the transaction history
+			events: []common.Hash{
+				ethtypes.SmartYield.BuyTokensEventID(),
+				ethtypes.SmartYield.SellTokensEventID(),
+				ethtypes.SmartYield.BuyJuniorBondEventID(),
+				ethtypes.SmartYield.RedeemJuniorBondEventID(),
+				ethtypes.SmartYield.BuySeniorBondEventID(),
+				ethtypes.SmartYield.RedeemSeniorBondEventID(),
+				ethtypes.SmartYieldCompoundController.HarvestEventID(),
+				ethtypes.SmartYieldCompoundProvider.TransferFeesEventID(),
+			},
+			tables: []string{
+				"smart_yield.junior_entry_events", "smart_yield.junior_instant_withdraw_events", "smart_yield.junior_2step_withdraw_events",
+				"smart_yield.junior_2step_redeem_events", "smart_yield.senior_entry_events", "smart_yield.senior_redeem_events",
+				"smart_yield.controller_harvests", "smart_yield.provider_transfer_fees",
+			},
+		},
+		{
+			name:    "smartYieldRewards",
+			enabled: s.SmartYield.Enabled,
+			events:  rewardPoolEvents(),
+			tables:  []string{"smart_yield.rewards_claims", "smart_yield.rewards_staking_actions"},
+		},
+		{
+			name:    "smartAlpha",
+			enabled: s.SmartAlpha.Enabled,
+			events: []common.Hash{
+				ethtypes.SmartAlpha.JuniorJoinEntryQueueEventID(),
+				ethtypes.SmartAlpha.JuniorJoinExitQueueEventID(),
+				ethtypes.SmartAlpha.JuniorRedeemTokensEventID(),
+				ethtypes.SmartAlpha.JuniorRedeemUnderlyingEventID(),
+				ethtypes.SmartAlpha.SeniorJoinEntryQueueEventID(),
+				ethtypes.SmartAlpha.SeniorJoinExitQueueEventID(),
+				ethtypes.SmartAlpha.SeniorRedeemTokensEventID(),
+				ethtypes.SmartAlpha.SeniorRedeemUnderlyingEventID(),
+				ethtypes.SmartAlpha.EpochEndEventID(),
+			},
+			tables: []string{
+				"smart_alpha.user_join_entry_queue_events", "smart_alpha.user_join_exit_queue_events", "smart_alpha.user_redeem_tokens_events",
+				"smart_alpha.user_redeem_underlying_events", "smart_alpha.epoch_end_events",
+			},
+		},
+		{
+			name:    "smartAlphaRewards",
+			enabled: s.SmartAlpha.Enabled,
+			events:  rewardPoolEvents(),
+			tables:  []string{"smart_alpha.rewards_claims", "smart_alpha.rewards_staking_actions"},
+		},
+	}
+}
+
+func rewardPoolEvents() []common.Hash {
+	return []common.Hash{
+		ethtypes.RewardPoolSingle.ClaimEventID(),
+		ethtypes.RewardPoolSingle.DepositEventID(),
+		ethtypes.RewardPoolSingle.WithdrawEventID(),
+		ethtypes.RewardPoolMulti.ClaimRewardTokenEventID(),
+	}
+}
+
+// verifyData compares the logs of the contracts tracked by the storables in [from, to] with the events stored for
+// them; the blocks that don't match are rolled back and returned, so they can be queued again
+func (c *Checker) verifyData(ctx context.Context, from, to int64) ([]int64, error) {
+	err := c.tm.RefreshCache(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not update state cache")
+	}
+
+	chunk := config.Store.Feature.Integrity.Data.ChunkSize
+	if chunk <= 0 {
+		chunk = 1000
+	}
+
+	var found []discrepancy
+	for start := from; start <= to; start += chunk {
+		end := start + chunk - 1
+		if end > to {
+			end = to
+		}
+
+		for _, check := range dataChecks() {
+			if !check.enabled {
+				continue
+			}
+
+			d, err := c.runDataCheck(ctx, check, start, end)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not run %s data check", check.name)
+			}
+
+			found = append(found, d...)
+		}
+	}
+
+	if len(found) == 0 {
+		c.logger.WithFields(logrus.Fields{"from": from, "to": to}).Debug("stored events match the chain")
+		return nil, nil
+	}
+
+	err = c.recordDiscrepancies(ctx, found)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := make(map[int64]bool)
+	var blocks []int64
+	for _, d := range found {
+		metricsDataDiscrepancies.WithLabelValues(d.check).Inc()
+
+		c.logger.WithFields(logrus.Fields{
+			"block":      d.block,
+			"check":      d.check,
+			"chain":      d.chainLogs,
+			"stored":     d.storedLogs,
+			"missing":    len(d.missing),
+			"unexpected": len(d.unexpected),
+		}).Warn("stored events do not match the chain")
+
+		if !unique[d.block] {
+			unique[d.block] = true
+			blocks = append(blocks, d.block)
+		}
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i] < blocks[j]
+	})
+
+	// the blocks are still in the database, so they would be skipped if they were only queued
+	err = processor.RollbackBlocks(ctx, c.db, c.tm, blocks)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not roll back inconsistent blocks")
+	}
+
+	return blocks, nil
+}
+
+func (c *Checker) runDataCheck(ctx context.Context, check dataCheck, from, to int64) ([]discrepancy, error) {
+	addresses := c.tm.EventSourceAddresses(check.name)
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	logs, err := eth.GetLogs(from, to, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(map[string]bool)
+	for _, e := range check.events {
+		events[strings.ToLower(e.String())] = true
+	}
+
+	// all the logs of the contracts and the ones that should be stored, by block
+	onChain := make(map[int64]map[string]bool)
+	expected := make(map[int64]map[string]bool)
+	for _, l := range logs {
+		block, err := strconv.ParseInt(l.BlockNumber, 0, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode log block number")
+		}
+
+		index, err := strconv.ParseInt(l.LogIndex, 0, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode log index")
+		}
+
+		key := logKey(l.TransactionHash, index)
+		addKey(onChain, block, key)
+
+		if len(l.Topics) == 0 || !events[strings.ToLower(l.Topics[0])] {
+			continue
+		}
+
+		if check.topics > 0 && len(l.Topics) != check.topics {
+			continue
+		}
+
+		addKey(expected, block, key)
+	}
+
+	stored, err := c.storedLogs(ctx, check.tables, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []discrepancy
+	for block := from; block <= to; block++ {
+		d := discrepancy{
+			block:      block,
+			check:      check.name,
+			chainLogs:  len(expected[block]),
+			storedLogs: len(stored[block]),
+			missing:    []string{},
+			unexpected: []string{},
+		}
+
+		for key := range expected[block] {
+			if !stored[block][key] {
+				d.missing = append(d.missing, key)
+			}
+		}
+
+		for key := range stored[block] {
+			if !onChain[block][key] {
+				d.unexpected = append(d.unexpected, key)
+			}
+		}
+
+		if len(d.missing) > 0 || len(d.unexpected) > 0 {
+			sort.Strings(d.missing)
+			sort.Strings(d.unexpected)
+			found = append(found, d)
+		}
+	}
+
+	return found, nil
+}
+
+// storedLogs returns the distinct (tx_hash, log_index) of the rows stored in the given tables, by block
+func (c *Checker) storedLogs(ctx context.Context, tables []string, from, to int64) (map[int64]map[string]bool, error) {
+	var selects []string
+	for _, t := range tables {
+		selects = append(selects, fmt.Sprintf(`select included_in_block, tx_hash, log_index from %s where included_in_block between $1 and $2`, t))
+	}
+
+	rows, err := c.db.Query(ctx, strings.Join(selects, " union "), from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get stored events")
+	}
+	defer rows.Close()
+
+	stored := make(map[int64]map[string]bool)
+	for rows.Next() {
+		var block, index int64
+		var txHash string
+
+		err := rows.Scan(&block, &txHash, &index)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan stored event")
+		}
+
+		addKey(stored, block, logKey(txHash, index))
+	}
+
+	return stored, rows.Err()
+}
+
+func (c *Checker) recordDiscrepancies(ctx context.Context, found []discrepancy) error {
+	var rows [][]interface{}
+	for _, d := range found {
+		rows = append(rows, []interface{}{d.block, d.check, d.chainLogs, d.storedLogs, d.missing, d.unexpected})
+	}
+
+	_, err := c.db.CopyFrom(
+		ctx,
+		pgx.Identifier{"public", "data_discrepancies"},
+		[]string{"block", "check_name", "chain_logs", "stored_logs", "missing", "unexpected"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not store data discrepancies")
+	}
+
+	return nil
+}
+
+func logKey(txHash string, index int64) string {
+	return fmt.Sprintf("%s:%d", utils.NormalizeAddress(txHash), index)
+}
+
+func addKey(set map[int64]map[string]bool, block int64, key string) {
+	if set[block] == nil {
+		set[block] = make(map[string]bool)
+	}
+
+	set[block][key] = true
+}
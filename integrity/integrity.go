@@ -13,6 +13,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/eth"
 	"github.com/barnbridge/meminero/state"
 	"github.com/lacasian/ethwheels/bestblock"
 )
@@ -107,19 +108,76 @@ func (c *Checker) lifecycle(ctx context.Context) error {
 		return nil
 	}
 
-	missing, err := c.checkMissingBlocks(ctx, checkpoint, highestBlock)
+	finalized, err := eth.FinalizedHeight(best)
+	if err != nil {
+		return errors.Wrap(err, "could not get finalized height")
+	}
+
+	if config.Store.Feature.Finality.Dual {
+		err = c.confirmBlocks(ctx, finalized)
+		if err != nil {
+			return err
+		}
+	}
+
+	// checkpoints are only set at finalized heights; the blocks above can still be reorged, so they are checked
+	// once they are final
+	end := highestBlock
+	if finalized < end {
+		end = finalized
+	}
+
+	if checkpoint >= end {
+		c.logger.Debug("no new finalized blocks to check")
+		return nil
+	}
+
+	// the data checks go through the logs of the whole range, so the checkpoint moves in steps
+	checkData := config.Store.Feature.Integrity.Data.Enabled
+	maxBlocks := config.Store.Feature.Integrity.Data.MaxBlocks
+	if checkData && maxBlocks > 0 && end > checkpoint+maxBlocks {
+		end = checkpoint + maxBlocks
+	}
+
+	missing, err := c.checkMissingBlocks(ctx, checkpoint, end)
 	if err != nil {
 		return err
 	}
 
-	broken, err := c.checkBrokenHashChain(ctx, checkpoint, highestBlock)
+	broken, err := c.checkBrokenHashChain(ctx, checkpoint, end)
 	if err != nil {
 		return err
 	}
 
 	all := append(missing, broken...)
+
+	// the stored chain is consistent below the first inconsistent block; that part is checked against the node, so the
+	// checkpoint only moves past blocks that are part of the canonical chain
+	consistent := end
+	for _, b := range all {
+		if b-1 < consistent {
+			consistent = b - 1
+		}
+	}
+
+	fork, err := c.checkCanonicalChain(ctx, checkpoint, consistent)
+	if err != nil {
+		return err
+	}
+
+	if fork > 0 {
+		all = append(all, fork)
+	}
+
+	if len(all) == 0 && checkData {
+		all, err = c.verifyData(ctx, checkpoint+1, end)
+		if err != nil {
+			return err
+		}
+	}
+
 	if len(all) == 0 {
-		_, err = c.db.Exec(ctx, "insert into public.integrity_checkpoints (number) values($1)", highestBlock)
+		_, err = c.db.Exec(ctx, "insert into public.integrity_checkpoints (number) values($1)", end)
 		if err != nil {
 			return errors.Wrap(err, "could not store new integrity checkpoint")
 		}
@@ -144,7 +202,17 @@ func (c *Checker) lifecycle(ctx context.Context) error {
 	})
 
 	for _, block := range blocks {
-		err = c.tm.AddTaskToQueue(block)
+		// blocks that are waiting for a retry keep their backoff and the dead-lettered ones need a manual requeue
+		f, err := c.tm.TaskFailure(block)
+		if err != nil {
+			return errors.Wrap(err, "could not check block failures")
+		}
+
+		if f != nil {
+			continue
+		}
+
+		err = c.tm.AddTaskToQueue(state.LaneRepair, block)
 		if err != nil {
 			return errors.Wrap(err, "could not queue block for rescrape")
 		}
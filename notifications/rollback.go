@@ -0,0 +1,59 @@
+package notifications
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// the notification and job types created by each storable; the jobs spawned by a job keep the block of the first one
+var (
+	GovernanceTypes = []string{
+		ProposalCreated, ProposalActivating, ProposalCanceled, ProposalVotingOpen, ProposalVotingEnding,
+		ProposalOutcome, ProposalAccepted, ProposalFailedQuorum, ProposalFailedVotes, ProposalQueued,
+		ProposalQueueEnding, ProposalGracePeriod, ProposalExecuted, ProposalExpires, ProposalExpired,
+		AbrogationProposalCreated, ProposalAbrogated,
+	}
+	DelegateTypes   = []string{DelegateStart}
+	SmartYieldTypes = []string{SmartYieldTokenBought}
+)
+
+// RollbackWithTx removes the notifications and the jobs of the given types that were created by a block
+// the jobs are removed whether they already ran or are still scheduled, so the future notifications are cancelled too
+func RollbackWithTx(ctx context.Context, tx pgx.Tx, block int64, types []string) error {
+	// wait for the worker to finish any of the jobs it is executing; the next jobs and the notifications it creates are
+	// only visible to the statements started after it commits
+	lock := `
+		select
+			id
+		from
+			public.notification_jobs
+		where
+			included_in_block = $1
+			and "type" = any($2)
+		for update
+		;
+	`
+	rows, err := tx.Query(ctx, lock, block, pq.Array(types))
+	if err != nil {
+		return errors.Wrap(err, "lock notification jobs")
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return errors.Wrap(rows.Err(), "lock notification jobs")
+	}
+
+	_, err = tx.Exec(ctx, `delete from public.notification_jobs where included_in_block = $1 and "type" = any($2);`, block, pq.Array(types))
+	if err != nil {
+		return errors.Wrap(err, "delete notification jobs")
+	}
+
+	_, err = tx.Exec(ctx, `delete from public.notifications where included_in_block = $1 and "type" = any($2);`, block, pq.Array(types))
+	if err != nil {
+		return errors.Wrap(err, "delete notifications")
+	}
+
+	return nil
+}
@@ -16,43 +16,59 @@ type Worker struct {
 	db *pgxpool.Pool
 }
 
+// Run polls for new jobs and executes them until the context is cancelled; it returns once the jobs being executed
+// were committed or rolled back
 func (w *Worker) Run(ctx context.Context) {
-	// poll for new jobs
-	go func() {
-		for {
-			select {
-			case <-time.After(time.Second):
-				tx, err := w.db.BeginTx(ctx, pgx.TxOptions{})
-				if err != nil {
-					log.Fatalf("start worker tx: %s", err)
+	for {
+		select {
+		case <-time.After(time.Second):
+			err := w.executeJobs(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					// the worker was stopped (e.g. it's not the leader anymore) while executing the jobs; the
+					// transaction was not committed, so the jobs will be picked up again
+					log.Info("received exit signal, stopping")
+					return
 				}
 
-				jobs, err := w.jobs(ctx, tx)
-				if err != nil {
-					log.Fatalf("failed to get jobs: %s", err)
-				}
+				log.Fatal(err)
+			}
 
-				err = ExecuteJobsWithTx(ctx, tx, jobs...)
-				if err != nil {
-					log.Fatalf("failed to execute jobs: %s", err)
-				}
+		case <-ctx.Done():
+			log.Info("received exit signal, stopping")
+			return
+		}
+	}
+}
 
-				err = SoftDeleteJobsWithTx(ctx, tx, jobs...)
-				if err != nil {
-					log.Fatalf("failed to cleanup jobs: %s", err)
-				}
+func (w *Worker) executeJobs(ctx context.Context) error {
+	tx, err := w.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return errors.Wrap(err, "start worker tx")
+	}
+	defer tx.Rollback(context.Background())
 
-				err = tx.Commit(ctx)
-				if err != nil {
-					log.Fatalf("failed to commit jobs: %s", err)
-				}
+	jobs, err := w.jobs(ctx, tx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get jobs")
+	}
 
-			case <-ctx.Done():
-				log.Info("received exit signal, stopping")
-				return
-			}
-		}
-	}()
+	err = ExecuteJobsWithTx(ctx, tx, jobs...)
+	if err != nil {
+		return errors.Wrap(err, "failed to execute jobs")
+	}
+
+	err = SoftDeleteJobsWithTx(ctx, tx, jobs...)
+	if err != nil {
+		return errors.Wrap(err, "failed to cleanup jobs")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to commit jobs")
+	}
+
+	return nil
 }
 
 func (w *Worker) jobs(ctx context.Context, tx pgx.Tx) ([]*Job, error) {
@@ -70,6 +86,7 @@ func (w *Worker) jobs(ctx context.Context, tx pgx.Tx) ([]*Job, error) {
 			"execute_on" < EXTRACT(EPOCH FROM NOW())::bigint
 			AND deleted = FALSE
 		LIMIT 1000
+		FOR UPDATE SKIP LOCKED
 		;
 	`
 	rows, err := tx.Query(ctx, sel)
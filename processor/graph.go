@@ -0,0 +1,345 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/processor/storables/accounterc20transfers"
+	"github.com/barnbridge/meminero/processor/storables/contractevents"
+	"github.com/barnbridge/meminero/processor/storables/dao/barn"
+	"github.com/barnbridge/meminero/processor/storables/dao/governance"
+	"github.com/barnbridge/meminero/processor/storables/erc20transfers"
+	saEvents "github.com/barnbridge/meminero/processor/storables/smartalpha/events"
+	saRewards "github.com/barnbridge/meminero/processor/storables/smartalpha/rewards"
+	saState "github.com/barnbridge/meminero/processor/storables/smartalpha/state"
+	seScrape "github.com/barnbridge/meminero/processor/storables/smartexposure/events"
+	sePools "github.com/barnbridge/meminero/processor/storables/smartexposure/pool_state"
+	seTranches "github.com/barnbridge/meminero/processor/storables/smartexposure/tranche_state"
+	syERC721 "github.com/barnbridge/meminero/processor/storables/smartyield/erc721"
+	syEvents "github.com/barnbridge/meminero/processor/storables/smartyield/events"
+	syRewards "github.com/barnbridge/meminero/processor/storables/smartyield/rewards"
+	syState "github.com/barnbridge/meminero/processor/storables/smartyield/state"
+	"github.com/barnbridge/meminero/processor/storables/tokenprices"
+	"github.com/barnbridge/meminero/processor/storables/yieldfarming"
+	"github.com/barnbridge/meminero/types"
+)
+
+// storableDef declares a storable to the processor
+type storableDef struct {
+	id      string
+	enabled func() bool
+
+	// requires are the storables that must be enabled along with this one (e.g. its queries use their tables); they
+	// don't have to run first
+	requires []string
+	// consumes are the storables whose Result is passed to this one (see types.Consumer); they run in an earlier stage
+	// and must be enabled as well
+	consumes []string
+
+	// sampling is the key in the storable config of the sampling applied to this contract state storable
+	sampling string
+
+	new func(p *Processor) types.Storable
+}
+
+func contractState() bool {
+	return config.Store.Feature.ContractState.Enabled
+}
+
+var storableDefs = []storableDef{
+	{
+		id:      accounterc20transfers.ID,
+		enabled: func() bool { return config.Store.Storable.AccountERC20Transfers.Enabled },
+		new:     func(p *Processor) types.Storable { return accounterc20transfers.New(p.Block, p.state) },
+	},
+	{
+		id:      erc20transfers.ID,
+		enabled: func() bool { return config.Store.Storable.Erc20Transfers.Enabled },
+		new:     func(p *Processor) types.Storable { return erc20transfers.New(p.Block, p.state) },
+	},
+	{
+		id:       tokenprices.ID,
+		enabled:  func() bool { return config.Store.Storable.TokenPrices.Enabled && contractState() },
+		sampling: "tokenPrices",
+		new:      func(p *Processor) types.Storable { return tokenprices.New(p.Block, p.state) },
+	},
+	{
+		id:      yieldfarming.ID,
+		enabled: func() bool { return config.Store.Storable.YieldFarming.Enabled },
+		new:     func(p *Processor) types.Storable { return yieldfarming.New(p.Block) },
+	},
+	{
+		id:       governance.ID,
+		enabled:  func() bool { return config.Store.Storable.Governance.Enabled },
+		requires: []string{barn.ID},
+		new:      func(p *Processor) types.Storable { return governance.New(p.Block) },
+	},
+	{
+		id:       barn.ID,
+		enabled:  func() bool { return config.Store.Storable.Barn.Enabled },
+		requires: []string{governance.ID},
+		new:      func(p *Processor) types.Storable { return barn.New(p.Block) },
+	},
+	{
+		id:      syEvents.ID,
+		enabled: func() bool { return config.Store.Storable.SmartYield.Enabled },
+		new:     func(p *Processor) types.Storable { return syEvents.New(p.Block, p.state) },
+	},
+	{
+		id:      syERC721.ID,
+		enabled: func() bool { return config.Store.Storable.SmartYield.Enabled },
+		new:     func(p *Processor) types.Storable { return syERC721.New(p.Block, p.state) },
+	},
+	{
+		id:      syRewards.ID,
+		enabled: func() bool { return config.Store.Storable.SmartYield.Enabled },
+		new:     func(p *Processor) types.Storable { return syRewards.New(p.Block, p.state) },
+	},
+	{
+		id:       syState.ID,
+		enabled:  func() bool { return config.Store.Storable.SmartYield.Enabled && contractState() },
+		requires: []string{erc20transfers.ID, tokenprices.ID},
+		sampling: "smartYield",
+		new:      func(p *Processor) types.Storable { return syState.New(p.Block, p.state) },
+	},
+	{
+		id:      seScrape.ID,
+		enabled: func() bool { return config.Store.Storable.SmartExposure.Enabled },
+		new:     func(p *Processor) types.Storable { return seScrape.New(p.Block, p.state) },
+	},
+	{
+		id:       seTranches.ID,
+		enabled:  func() bool { return config.Store.Storable.SmartExposure.Enabled && contractState() },
+		requires: []string{erc20transfers.ID},
+		consumes: []string{tokenprices.ID},
+		sampling: "smartExposure",
+		new:      func(p *Processor) types.Storable { return seTranches.New(p.Block, p.state) },
+	},
+	{
+		id:       sePools.ID,
+		enabled:  func() bool { return config.Store.Storable.SmartExposure.Enabled && contractState() },
+		requires: []string{erc20transfers.ID},
+		consumes: []string{tokenprices.ID},
+		sampling: "smartExposure",
+		new:      func(p *Processor) types.Storable { return sePools.New(p.Block, p.state) },
+	},
+	{
+		id:      saEvents.ID,
+		enabled: func() bool { return config.Store.Storable.SmartAlpha.Enabled },
+		new:     func(p *Processor) types.Storable { return saEvents.New(p.Block, p.state) },
+	},
+	{
+		id:      saRewards.ID,
+		enabled: func() bool { return config.Store.Storable.SmartAlpha.Enabled },
+		new:     func(p *Processor) types.Storable { return saRewards.New(p.Block, p.state) },
+	},
+	{
+		id:       saState.ID,
+		enabled:  func() bool { return config.Store.Storable.SmartAlpha.Enabled && contractState() },
+		requires: []string{erc20transfers.ID, tokenprices.ID},
+		sampling: "smartAlpha",
+		new:      func(p *Processor) types.Storable { return saState.New(p.Block, p.state) },
+	},
+	{
+		id:      contractevents.ID,
+		enabled: func() bool { return config.Store.Storable.ContractEvents.Enabled },
+		new:     func(p *Processor) types.Storable { return contractevents.New(p.Block) },
+	},
+}
+
+// GraphNode is a storable in the storable graph
+type GraphNode struct {
+	ID       string
+	Enabled  bool
+	Requires []string
+	Consumes []string
+	Sampling string
+}
+
+// Graph is the storable graph built from the declarations of the storables
+// Stages lists the IDs of the enabled storables by execution stage: the storables in a stage are executed in parallel
+// and only consume the results of the storables in the previous stages.
+type Graph struct {
+	Nodes  []GraphNode
+	Stages [][]string
+
+	defs map[string]storableDef
+}
+
+var (
+	graphOnce sync.Once
+	graph     *Graph
+	graphErr  error
+)
+
+// StorableGraph builds the storable graph for the current config and validates it; the result is cached since the
+// config does not change at runtime
+func StorableGraph() (*Graph, error) {
+	graphOnce.Do(func() {
+		graph, graphErr = buildGraph(storableDefs)
+	})
+
+	return graph, graphErr
+}
+
+func buildGraph(defs []storableDef) (*Graph, error) {
+	g := &Graph{defs: make(map[string]storableDef)}
+
+	for _, d := range defs {
+		if _, exists := g.defs[d.id]; exists {
+			return nil, errors.Errorf("storable %s is declared twice", d.id)
+		}
+
+		g.defs[d.id] = d
+	}
+
+	enabled := make(map[string]bool)
+	for _, d := range defs {
+		enabled[d.id] = d.enabled()
+
+		g.Nodes = append(g.Nodes, GraphNode{
+			ID:       d.id,
+			Enabled:  enabled[d.id],
+			Requires: d.requires,
+			Consumes: d.consumes,
+			Sampling: d.sampling,
+		})
+	}
+
+	for _, d := range defs {
+		for _, dep := range append(append([]string{}, d.requires...), d.consumes...) {
+			if _, exists := g.defs[dep]; !exists {
+				return nil, errors.Errorf("storable %s depends on %s, which is not a known storable", d.id, dep)
+			}
+
+			if enabled[d.id] && !enabled[dep] {
+				return nil, errors.Errorf("storable %s depends on %s, which is disabled (enable %s or disable %s)", d.id, dep, dep, d.id)
+			}
+		}
+	}
+
+	// the stage of a storable is the length of the longest chain of storables it consumes the results of
+	stage := make(map[string]int)
+	var visit func(id string, path []string) (int, error)
+	visit = func(id string, path []string) (int, error) {
+		for i, p := range path {
+			if p == id {
+				return 0, errors.Errorf("storables consume each other's results: %s", strings.Join(append(path[i:], id), " -> "))
+			}
+		}
+
+		if s, done := stage[id]; done {
+			return s, nil
+		}
+
+		s := 0
+		for _, dep := range g.defs[id].consumes {
+			ds, err := visit(dep, append(path, id))
+			if err != nil {
+				return 0, err
+			}
+
+			if ds+1 > s {
+				s = ds + 1
+			}
+		}
+
+		stage[id] = s
+
+		return s, nil
+	}
+
+	for _, d := range defs {
+		if !enabled[d.id] {
+			continue
+		}
+
+		s, err := visit(d.id, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for len(g.Stages) <= s {
+			g.Stages = append(g.Stages, nil)
+		}
+
+		g.Stages[s] = append(g.Stages[s], d.id)
+	}
+
+	for _, ids := range g.Stages {
+		sort.Strings(ids)
+	}
+
+	return g, nil
+}
+
+// Dot returns the graph in the graphviz format; the results passed between storables are solid edges and the other
+// requirements are dashed
+func (g *Graph) Dot() string {
+	var b strings.Builder
+
+	b.WriteString("digraph storables {\n")
+	b.WriteString("  rankdir=LR;\n")
+
+	for _, n := range g.Nodes {
+		style := ""
+		if !n.Enabled {
+			style = ", style=dotted"
+		}
+
+		fmt.Fprintf(&b, "  %q [label=%q%s];\n", n.ID, n.ID, style)
+	}
+
+	for _, n := range g.Nodes {
+		for _, c := range n.Consumes {
+			fmt.Fprintf(&b, "  %q -> %q [label=\"result\"];\n", c, n.ID)
+		}
+
+		for _, r := range n.Requires {
+			fmt.Fprintf(&b, "  %q -> %q [style=dashed];\n", r, n.ID)
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// closure returns the given storables (true) and, transitively, the storables whose results they consume (false)
+func (g *Graph) closure(ids []string) (map[string]bool, error) {
+	result := make(map[string]bool)
+
+	for _, id := range ids {
+		d, exists := g.defs[id]
+		if !exists {
+			return nil, errors.Errorf("unknown storable %s", id)
+		}
+
+		if !d.enabled() {
+			return nil, errors.Errorf("storable %s is disabled", id)
+		}
+
+		result[id] = true
+	}
+
+	queue := append([]string{}, ids...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		// the graph was validated, so the consumed storables are enabled
+		for _, dep := range g.defs[id].consumes {
+			if _, seen := result[dep]; !seen {
+				result[dep] = false
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	return result, nil
+}
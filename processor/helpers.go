@@ -5,6 +5,9 @@ import (
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/eth"
 )
 
 // checkBlockExists verifies if the current block matches any other block in the database by hash
@@ -22,19 +25,19 @@ func (p *Processor) checkBlockExists(ctx context.Context, db *pgxpool.Pool) (boo
 	return false, nil
 }
 
-// checkBlockReorged verifies if the current block matches any block in the database on number
-// this is meant to be used in order to detect if the database contains a blocks with the same number
-// but different hash if the checkBlockExists function returns false
-func (p *Processor) checkBlockReorged(ctx context.Context, db *pgxpool.Pool) (bool, error) {
-	var count int
-	err := db.QueryRow(ctx, `select count(*) from blocks where number = $1`, p.Block.Number).Scan(&count)
-	if err != nil {
-		return false, errors.Wrap(err, "could not query database for block with same number")
+// isFinal returns false if the block is stored before it's final (dual finality mode), so it's marked as unconfirmed
+// until the integrity checker confirms it
+func (p *Processor) isFinal() bool {
+	if !config.Store.Feature.Finality.Dual {
+		return true
 	}
 
-	if count > 0 {
-		return true, nil
+	// the best block is not known here, but the block itself is a lower bound for it
+	finalized, err := eth.FinalizedHeight(p.Block.Number)
+	if err != nil {
+		p.logger.WithField("block", p.Block.Number).Warnf("could not get finalized height; marking block as unconfirmed: %s", err)
+		return false
 	}
 
-	return false, nil
+	return p.Block.Number <= finalized
 }
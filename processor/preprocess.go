@@ -24,6 +24,11 @@ func (p *Processor) preprocess() error {
 		return errors.Wrap(err, "could not parse receipts")
 	}
 
+	err = p.parseLogs()
+	if err != nil {
+		return errors.Wrap(err, "could not parse logs")
+	}
+
 	return nil
 }
 
@@ -86,6 +91,43 @@ func (p *Processor) parseReceipts() error {
 	return nil
 }
 
+// parseLogs groups the logs of a block scraped in log mode by transaction; the resulting transactions only
+// contain the hash and index besides the log entries
+func (p *Processor) parseLogs() error {
+	txs := make(map[string]*types.Tx)
+
+	for _, log := range p.Raw.Logs {
+		logEntry, err := p.parseLog(log)
+		if err != nil {
+			return errors.Wrap(err, "could not parse log")
+		}
+
+		txHash := utils.NormalizeAddress(log.TransactionHash)
+
+		tx, exists := txs[txHash]
+		if !exists {
+			tx = &types.Tx{
+				TxHash:            txHash,
+				IncludedInBlock:   p.Block.Number,
+				TxIndex:           int64(logEntry.TxIndex),
+				BlockCreationTime: p.Block.BlockCreationTime,
+			}
+			txs[txHash] = tx
+		}
+
+		tx.LogEntries = append(tx.LogEntries, logEntry)
+	}
+
+	for _, tx := range txs {
+		sort.Sort(tx.LogEntries)
+		p.Block.Txs = append(p.Block.Txs, *tx)
+	}
+
+	sort.Sort(p.Block.Txs)
+
+	return nil
+}
+
 func (p *Processor) parseTx(tx web3types.Transaction, receipt web3types.Receipt) (*types.Tx, error) {
 	sTx := &types.Tx{}
 	sTx.IncludedInBlock = p.Block.Number
@@ -25,6 +25,13 @@ type Processor struct {
 	logger *logrus.Entry
 
 	storables []types.Storable
+	// stages are the registered storables grouped by the stage of the storable graph they are executed in
+	stages [][]types.Storable
+	// samples are the sampling decisions for the current block, by key in the storable config
+	samples map[string]bool
+
+	// rollbackOnly is set on the processors created to roll back orphaned blocks, which have no data to process
+	rollbackOnly bool
 }
 
 func New(raw *types.RawData, state *state.Manager) (*Processor, error) {
@@ -39,6 +46,8 @@ func New(raw *types.RawData, state *state.Manager) (*Processor, error) {
 		return nil, err
 	}
 
+	rememberTimestamp(p.Block.Number, p.Block.BlockCreationTime)
+
 	p.registerStorables()
 
 	return p, nil
@@ -50,8 +59,15 @@ func (p *Processor) rollbackAll(ctx context.Context, db *pgxpool.Pool) error {
 		return errors.Wrap(err, "could not start database transaction")
 	}
 
+	err = state.CheckFence(ctx, tx, p.Block.Number)
+	if err != nil {
+		tx.Rollback(context.Background())
+		return err
+	}
+
 	_, err = tx.Exec(ctx, "delete from blocks where number = $1", p.Block.Number)
 	if err != nil {
+		tx.Rollback(context.Background())
 		return errors.Wrap(err, "could not remove block from database")
 	}
 
@@ -83,6 +99,114 @@ func (p *Processor) rollbackAll(ctx context.Context, db *pgxpool.Pool) error {
 
 // Store will open a database transaction and execute all the registered Storables in the said transaction
 func (p *Processor) Store(ctx context.Context, db *pgxpool.Pool) (bool, error) {
+	proceed, err := p.prepare(ctx, db)
+	if err != nil || !proceed {
+		return false, err
+	}
+
+	err = p.execute(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = p.storeAll(ctx, db)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// StoreBatch does the same thing as Store for a window of blocks but saves all of them in a single database transaction
+// every block is saved under its own savepoint, so a block that fails to save does not affect the others in the batch
+// and each block keeps its own rows that can be rolled back individually
+// the returned slices are indexed the same as processors
+func StoreBatch(ctx context.Context, db *pgxpool.Pool, processors []*Processor) ([]bool, []error) {
+	saved := make([]bool, len(processors))
+	errs := make([]error, len(processors))
+
+	var toSave []int
+	for i, p := range processors {
+		proceed, err := p.prepare(ctx, db)
+		if err != nil || !proceed {
+			errs[i] = err
+			continue
+		}
+
+		err = p.execute(ctx)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		toSave = append(toSave, i)
+	}
+
+	if len(toSave) == 0 {
+		return saved, errs
+	}
+
+	start := time.Now()
+	logger := logrus.WithField("module", "processor").WithField("blocks", len(toSave))
+	logger.Info("storing batch to database")
+
+	fail := func(err error) ([]bool, []error) {
+		for _, i := range toSave {
+			saved[i] = false
+			errs[i] = err
+		}
+
+		return saved, errs
+	}
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fail(errors.Wrap(err, "could not start database transaction"))
+	}
+
+	for _, i := range toSave {
+		p := processors[i]
+
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			tx.Rollback(ctx)
+			return fail(errors.Wrap(err, "could not create savepoint"))
+		}
+
+		err = p.save(ctx, sp)
+		if err != nil {
+			errs[i] = errors.Wrap(err, "could not save block in batch")
+
+			rollbackErr := sp.Rollback(ctx)
+			if rollbackErr != nil {
+				tx.Rollback(ctx)
+				return fail(errors.Wrap(rollbackErr, "could not rollback to savepoint"))
+			}
+
+			continue
+		}
+
+		err = sp.Commit(ctx)
+		if err != nil {
+			tx.Rollback(ctx)
+			return fail(errors.Wrap(err, "could not release savepoint"))
+		}
+
+		saved[i] = true
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fail(errors.Wrap(err, "could not save batch to db"))
+	}
+
+	logger.WithField("duration", time.Since(start)).Info("done storing batch to database")
+
+	return saved, errs
+}
+
+// prepare checks if the block should be stored and removes any old version of it from the database
+func (p *Processor) prepare(ctx context.Context, db *pgxpool.Pool) (bool, error) {
 	exists, err := p.checkBlockExists(ctx, db)
 	if err != nil {
 		return false, err
@@ -101,60 +225,79 @@ func (p *Processor) Store(ctx context.Context, db *pgxpool.Pool) (bool, error) {
 			return false, err
 		}
 	} else {
-		reorged, err := p.checkBlockReorged(ctx, db)
+		r, err := p.detectReorg(ctx, db)
 		if err != nil {
 			return false, err
 		}
 
-		if reorged {
-			p.logger.WithField("block", p.Block.Number).Warn("detected reorged block")
-
-			err = p.rollbackAll(ctx, db)
+		if r != nil {
+			err = p.rollbackReorg(ctx, db, r)
 			if err != nil {
 				return false, err
 			}
 		}
 	}
 
+	return true, nil
+}
+
+func (p *Processor) execute(ctx context.Context) error {
 	start := time.Now()
 	p.logger.Info("executing storables")
 
-	wg, _ := errgroup.WithContext(ctx)
+	g, err := StorableGraph()
+	if err != nil {
+		return err
+	}
 
-	for _, s := range p.storables {
-		s := s
+	results := make(map[string]interface{})
 
-		wg.Go(func() error {
-			log := logrus.WithField("module", fmt.Sprintf("storable(%s)", s.ID()))
+	for _, stage := range p.stages {
+		wg, _ := errgroup.WithContext(ctx)
 
-			log.Trace("executing")
-			start := time.Now()
+		for _, s := range stage {
+			s := s
 
-			err = s.Execute(ctx)
-			if err != nil {
-				return err
+			if c, ok := s.(types.Consumer); ok {
+				consumed := make(map[string]interface{})
+				for _, id := range g.defs[s.ID()].consumes {
+					consumed[id] = results[id]
+				}
+
+				c.Consume(consumed)
 			}
 
-			recordExecuteDuration(s.ID(), start)
-			log.WithField("duration", time.Since(start)).Trace("done executing")
+			wg.Go(func() error {
+				log := logrus.WithField("module", fmt.Sprintf("storable(%s)", s.ID()))
 
-			return nil
-		})
-	}
+				log.Trace("executing")
+				start := time.Now()
 
-	err = wg.Wait()
-	if err != nil {
-		return false, errors.Wrap(err, "got error executing storables")
-	}
+				err := s.Execute(ctx)
+				if err != nil {
+					return err
+				}
 
-	p.logger.WithField("duration", time.Since(start)).Info("done executing storables")
+				recordExecuteDuration(s.ID(), start)
+				log.WithField("duration", time.Since(start)).Trace("done executing")
 
-	err = p.storeAll(ctx, db)
-	if err != nil {
-		return false, err
+				return nil
+			})
+		}
+
+		err := wg.Wait()
+		if err != nil {
+			return errors.Wrap(err, "got error executing storables")
+		}
+
+		for _, s := range stage {
+			results[s.ID()] = s.Result()
+		}
 	}
 
-	return true, nil
+	p.logger.WithField("duration", time.Since(start)).Info("done executing storables")
+
+	return nil
 }
 
 func (p *Processor) storeAll(ctx context.Context, db *pgxpool.Pool) error {
@@ -169,12 +312,33 @@ func (p *Processor) storeAll(ctx context.Context, db *pgxpool.Pool) error {
 		return errors.Wrap(err, "could not start database transaction")
 	}
 
-	err = p.storeBlock(ctx, tx)
+	err = p.save(ctx, tx)
 	if err != nil {
 		tx.Rollback(ctx)
 		return err
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not save data to db")
+	}
+
+	return nil
+}
+
+// save writes the block and the data of all the storables using the given transaction
+// it fails with state.ErrLockLost if another worker took over the block in the meantime
+func (p *Processor) save(ctx context.Context, tx pgx.Tx) error {
+	err := state.CheckFence(ctx, tx, p.Block.Number)
+	if err != nil {
+		return err
+	}
+
+	err = p.storeBlock(ctx, tx)
+	if err != nil {
+		return err
+	}
+
 	for _, s := range p.storables {
 		log := logrus.WithField("module", fmt.Sprintf("storable(%s)", s.ID()))
 
@@ -184,7 +348,6 @@ func (p *Processor) storeAll(ctx context.Context, db *pgxpool.Pool) error {
 
 		err = s.SaveToDatabase(ctx, tx)
 		if err != nil {
-			tx.Rollback(ctx)
 			return err
 		}
 
@@ -192,11 +355,6 @@ func (p *Processor) storeAll(ctx context.Context, db *pgxpool.Pool) error {
 		log.WithField("duration", time.Since(start)).Trace("done saving")
 	}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		return errors.Wrap(err, "could not save data to db")
-	}
-
 	return nil
 }
 
@@ -207,7 +365,7 @@ func (p *Processor) storeBlock(ctx context.Context, tx pgx.Tx) error {
 
 	b := p.Block
 
-	_, err := tx.Exec(ctx, "insert into blocks(number,block_hash,parent_block_hash,block_creation_time) values($1,$2,$3,$4)", b.Number, b.BlockHash, b.ParentBlockHash, b.BlockCreationTime)
+	_, err := tx.Exec(ctx, "insert into blocks(number,block_hash,parent_block_hash,block_creation_time,confirmed) values($1,$2,$3,$4,$5)", b.Number, b.BlockHash, b.ParentBlockHash, b.BlockCreationTime, p.isFinal())
 	if err != nil {
 		return err
 	}
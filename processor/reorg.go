@@ -0,0 +1,278 @@
+package processor
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/eth"
+	"github.com/barnbridge/meminero/state"
+	"github.com/barnbridge/meminero/types"
+)
+
+var (
+	metricsReorgs = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "processor_reorgs",
+		Help: "Number of reorgs detected and rolled back",
+	})
+	metricsReorgDepth = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "processor_reorg_depth",
+		Help:    "Number of orphaned blocks rolled back per reorg",
+		Buckets: []float64{1, 2, 3, 5, 10, 20, 50, 100},
+	})
+)
+
+type storedBlock struct {
+	Number          int64
+	BlockHash       string
+	ParentBlockHash string
+}
+
+// reorg is the set of stored blocks orphaned by the chain the current block belongs to
+type reorg struct {
+	// orphans are ordered by number and start right after the common ancestor
+	orphans   []storedBlock
+	newHashes []string
+}
+
+// detectReorg checks the current block against the stored chain; it returns nil if the block does not conflict
+// with it
+// The stored chain is walked back from the parent of the block until a stored block matches the hash expected by its
+// child (the common ancestor) and walked forward from the block while the stored blocks descend from orphaned ones.
+func (p *Processor) detectReorg(ctx context.Context, db *pgxpool.Pool) (*reorg, error) {
+	var orphans []storedBlock
+	newHashes := make(map[int64]string)
+
+	current, err := getStoredBlock(ctx, db, p.Block.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	if current != nil && current.BlockHash != p.Block.BlockHash {
+		orphans = append(orphans, *current)
+		newHashes[current.Number] = p.Block.BlockHash
+	}
+
+	// backwards: the parent of every canonical block is fetched from the node until a stored block matches it
+	expected := p.Block.ParentBlockHash
+	for n := p.Block.Number - 1; n >= 0; n-- {
+		b, err := getStoredBlock(ctx, db, n)
+		if err != nil {
+			return nil, err
+		}
+
+		// a gap in the stored chain; the blocks below it are checked when the missing ones are processed
+		if b == nil || b.BlockHash == expected {
+			break
+		}
+
+		if int64(len(orphans)) >= config.Store.Feature.Reorg.MaxDepth {
+			return nil, errors.Errorf("reorg at block %d is deeper than %d blocks", p.Block.Number, config.Store.Feature.Reorg.MaxDepth)
+		}
+
+		orphans = append([]storedBlock{*b}, orphans...)
+		newHashes[n] = expected
+
+		_, expected, err = eth.GetBlockHashes(n)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get canonical block")
+		}
+	}
+
+	if len(orphans) == 0 {
+		return nil, nil
+	}
+
+	// forwards: the stored descendants of the orphaned blocks are orphaned as well
+	last := orphans[len(orphans)-1].BlockHash
+	if current == nil {
+		// the old version of the block is not stored, so its stored descendants can't be linked to the orphaned chain;
+		// the integrity checker finds them through the broken hash chain
+		last = ""
+	}
+
+	for n := p.Block.Number + 1; last != ""; n++ {
+		b, err := getStoredBlock(ctx, db, n)
+		if err != nil {
+			return nil, err
+		}
+
+		if b == nil || b.ParentBlockHash != last {
+			break
+		}
+
+		if int64(len(orphans)) >= config.Store.Feature.Reorg.MaxDepth {
+			return nil, errors.Errorf("reorg at block %d is deeper than %d blocks", p.Block.Number, config.Store.Feature.Reorg.MaxDepth)
+		}
+
+		orphans = append(orphans, *b)
+		last = b.BlockHash
+	}
+
+	r := &reorg{orphans: orphans}
+	for _, o := range orphans {
+		h, exists := newHashes[o.Number]
+		if !exists {
+			h, _, err = eth.GetBlockHashes(o.Number)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not get canonical block")
+			}
+		}
+
+		r.newHashes = append(r.newHashes, h)
+	}
+
+	return r, nil
+}
+
+// rollbackReorg removes all the orphaned blocks through the Rollback of every storable in a single transaction,
+// records the reorg and queues the replacements of the orphaned blocks other than the current one
+func (p *Processor) rollbackReorg(ctx context.Context, db *pgxpool.Pool, r *reorg) error {
+	ancestor := r.orphans[0].Number - 1
+	log := p.logger.WithFields(logrus.Fields{
+		"block":           p.Block.Number,
+		"common-ancestor": ancestor,
+		"depth":           len(r.orphans),
+	})
+	log.Warn("detected reorg; rolling back orphaned blocks")
+
+	start := time.Now()
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return errors.Wrap(err, "could not start database transaction")
+	}
+	defer tx.Rollback(context.Background())
+
+	err = state.CheckFence(ctx, tx, p.Block.Number)
+	if err != nil {
+		return err
+	}
+
+	var oldHashes []string
+	for _, o := range r.orphans {
+		oldHashes = append(oldHashes, o.BlockHash)
+
+		_, err = tx.Exec(ctx, "delete from blocks where number = $1", o.Number)
+		if err != nil {
+			return errors.Wrap(err, "could not remove block from database")
+		}
+
+		for _, s := range p.rollbackStorables(o.Number) {
+			err = s.Rollback(ctx, tx)
+			if err != nil {
+				return errors.Wrapf(err, "could not roll back block %d", o.Number)
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		insert into public.reorgs (detected_at, common_ancestor, depth, old_hashes, new_hashes)
+		values ($1, $2, $3, $4, $5)
+	`, p.Block.Number, ancestor, len(r.orphans), oldHashes, r.newHashes)
+	if err != nil {
+		return errors.Wrap(err, "could not record reorg")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not commit reorg rollback")
+	}
+
+	metricsReorgs.Inc()
+	metricsReorgDepth.Observe(float64(len(r.orphans)))
+	log.WithField("duration", time.Since(start)).Info("rolled back orphaned blocks")
+
+	if !p.state.HasQueue() {
+		// e.g. scrape range, which goes over the replacements by itself
+		return nil
+	}
+
+	for _, o := range r.orphans {
+		if o.Number == p.Block.Number {
+			continue
+		}
+
+		err = p.state.AddTaskToQueue(state.LaneHead, o.Number)
+		if err != nil {
+			return errors.Wrap(err, "could not queue replacement block")
+		}
+	}
+
+	return nil
+}
+
+// rollbackStorables returns the storables of an orphaned block; only their Rollback is used, which just needs the
+// block number
+func (p *Processor) rollbackStorables(number int64) []types.Storable {
+	if number == p.Block.Number {
+		return p.storables
+	}
+
+	return newRollbackOnly(p.state, number).storables
+}
+
+// newRollbackOnly returns a processor that can only roll back the given block
+func newRollbackOnly(state *state.Manager, number int64) *Processor {
+	p := &Processor{
+		Block:        &types.Block{Number: number},
+		state:        state,
+		logger:       logrus.WithField("module", "processor"),
+		rollbackOnly: true,
+	}
+	p.registerStorables()
+
+	return p
+}
+
+// RollbackBlocks removes the given blocks and their data from the database in a single transaction, so they are
+// processed from scratch once they are queued again (an existing block would be skipped otherwise)
+func RollbackBlocks(ctx context.Context, db *pgxpool.Pool, state *state.Manager, numbers []int64) error {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return errors.Wrap(err, "could not start database transaction")
+	}
+	defer tx.Rollback(context.Background())
+
+	for _, n := range numbers {
+		_, err = tx.Exec(ctx, "delete from blocks where number = $1", n)
+		if err != nil {
+			return errors.Wrap(err, "could not remove block from database")
+		}
+
+		for _, s := range newRollbackOnly(state, n).storables {
+			err = s.Rollback(ctx, tx)
+			if err != nil {
+				return errors.Wrapf(err, "could not roll back block %d", n)
+			}
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not commit rollback transaction")
+	}
+
+	return nil
+}
+
+func getStoredBlock(ctx context.Context, db *pgxpool.Pool, number int64) (*storedBlock, error) {
+	b := storedBlock{Number: number}
+
+	err := db.QueryRow(ctx, `select block_hash, parent_block_hash from blocks where number = $1`, number).Scan(&b.BlockHash, &b.ParentBlockHash)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get stored block")
+	}
+
+	return &b, nil
+}
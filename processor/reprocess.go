@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/types"
+)
+
+// Reprocess executes the given storables on the block again and replaces their data in the database (Rollback, then
+// SaveToDatabase); the storables whose results they consume are executed as well, but their data is left as it is
+// it returns false if the block is not stored (or a different version of it is), since the data of the storables
+// must belong to a stored block
+func (p *Processor) Reprocess(ctx context.Context, db *pgxpool.Pool, ids []string) (bool, error) {
+	g, err := StorableGraph()
+	if err != nil {
+		return false, err
+	}
+
+	selected, err := g.closure(ids)
+	if err != nil {
+		return false, err
+	}
+
+	var stages [][]types.Storable
+	for _, stage := range p.stages {
+		var kept []types.Storable
+		for _, s := range stage {
+			if _, needed := selected[s.ID()]; needed {
+				kept = append(kept, s)
+			}
+		}
+
+		stages = append(stages, kept)
+	}
+	p.stages = stages
+
+	err = p.execute(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, errors.Wrap(err, "could not start database transaction")
+	}
+
+	// the lock keeps the block from being replaced by a scraper in the meantime
+	var exists bool
+	err = tx.QueryRow(ctx, `select exists(select 1 from blocks where number = $1 and block_hash = $2 for update)`, p.Block.Number, p.Block.BlockHash).Scan(&exists)
+	if err != nil {
+		tx.Rollback(context.Background())
+		return false, errors.Wrap(err, "could not lock block")
+	}
+
+	if !exists {
+		tx.Rollback(context.Background())
+		return false, nil
+	}
+
+	for _, stage := range p.stages {
+		for _, s := range stage {
+			if !selected[s.ID()] {
+				continue
+			}
+
+			log := logrus.WithField("module", fmt.Sprintf("storable(%s)", s.ID()))
+			start := time.Now()
+
+			err = s.Rollback(ctx, tx)
+			if err != nil {
+				tx.Rollback(context.Background())
+				return false, err
+			}
+
+			err = s.SaveToDatabase(ctx, tx)
+			if err != nil {
+				tx.Rollback(context.Background())
+				return false, err
+			}
+
+			log.WithField("duration", time.Since(start)).Trace("done reprocessing")
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "could not commit reprocessing transaction")
+	}
+
+	return true, nil
+}
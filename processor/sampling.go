@@ -0,0 +1,167 @@
+package processor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/eth"
+	"github.com/barnbridge/meminero/types"
+	"github.com/barnbridge/meminero/utils"
+)
+
+// timestampsCacheSize is the number of block timestamps kept in memory for the `seconds` sampling mode; while
+// following the chain, the parent of a block was almost always processed right before it
+const timestampsCacheSize = 1000
+
+var (
+	timestampsMu sync.Mutex
+	timestamps   = make(map[int64]int64)
+)
+
+// sampled wraps a contract state storable so it only takes a snapshot on the blocks selected by its sampling config
+// it's registered on every block regardless, so the snapshot of an old version of a block can still be rolled back
+type sampled struct {
+	types.Storable
+	skip bool
+}
+
+func (s *sampled) Execute(ctx context.Context) error {
+	if s.skip {
+		return nil
+	}
+
+	return s.Storable.Execute(ctx)
+}
+
+func (s *sampled) SaveToDatabase(ctx context.Context, tx pgx.Tx) error {
+	if s.skip {
+		return nil
+	}
+
+	return s.Storable.SaveToDatabase(ctx, tx)
+}
+
+func (s *sampled) Result() interface{} {
+	if s.skip {
+		return nil
+	}
+
+	return s.Storable.Result()
+}
+
+func (s *sampled) Consume(results map[string]interface{}) {
+	if c, ok := s.Storable.(types.Consumer); ok && !s.skip {
+		c.Consume(results)
+	}
+}
+
+// withSampling wraps a contract state storable of `name` (its key in the storable config) with the sampling decision
+// for the current block; the decision is made once per name, so all the storables of a product snapshot the same blocks
+func (p *Processor) withSampling(name string, sampling config.Sampling, s types.Storable) types.Storable {
+	if p.rollbackOnly {
+		return s
+	}
+
+	take, decided := p.samples[name]
+	if !decided {
+		var err error
+
+		take, err = p.shouldSample(name, sampling)
+		if err != nil {
+			// not knowing is not a reason to lose a snapshot
+			p.logger.WithField("storable", name).Errorf("could not check sampling, taking a snapshot: %s", err)
+			take = true
+		}
+
+		if p.samples == nil {
+			p.samples = make(map[string]bool)
+		}
+		p.samples[name] = take
+	}
+
+	return &sampled{Storable: s, skip: !take}
+}
+
+func (p *Processor) shouldSample(name string, sampling config.Sampling) (bool, error) {
+	switch sampling.Mode {
+	case "", config.SamplingEveryBlock:
+		return true, nil
+	case config.SamplingBlocks:
+		if sampling.Interval <= 0 {
+			logrus.Fatalf("invalid sampling interval for %s: %d", name, sampling.Interval)
+		}
+
+		return p.Block.Number%sampling.Interval == 0, nil
+	case config.SamplingSeconds:
+		if sampling.Interval <= 0 {
+			logrus.Fatalf("invalid sampling interval for %s: %d", name, sampling.Interval)
+		}
+
+		// the first block of every interval is sampled; deciding based on the parent instead of the last snapshot
+		// keeps the result the same no matter the order in which the blocks are processed
+		parent, err := blockTimestamp(p.Block.Number - 1)
+		if err != nil {
+			return false, err
+		}
+
+		return p.Block.BlockCreationTime/sampling.Interval != parent/sampling.Interval, nil
+	case config.SamplingEvents:
+		addresses := make(map[string]bool)
+		for _, a := range p.state.ContractStateAddresses(name) {
+			addresses[a] = true
+		}
+
+		for _, tx := range p.Block.Txs {
+			for _, l := range tx.LogEntries {
+				if addresses[utils.NormalizeAddress(l.Address.String())] {
+					return true, nil
+				}
+			}
+		}
+
+		return false, nil
+	default:
+		logrus.Fatalf("invalid sampling mode for %s: %s", name, sampling.Mode)
+	}
+
+	return false, nil
+}
+
+func rememberTimestamp(block int64, ts int64) {
+	timestampsMu.Lock()
+	defer timestampsMu.Unlock()
+
+	timestamps[block] = ts
+
+	if len(timestamps) > timestampsCacheSize {
+		for b := range timestamps {
+			if b < block-timestampsCacheSize {
+				delete(timestamps, b)
+			}
+		}
+	}
+}
+
+func blockTimestamp(block int64) (int64, error) {
+	timestampsMu.Lock()
+	ts, exists := timestamps[block]
+	timestampsMu.Unlock()
+
+	if exists {
+		return ts, nil
+	}
+
+	ts, err := eth.GetBlockTimestamp(block)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not get parent block timestamp")
+	}
+
+	rememberTimestamp(block, ts)
+
+	return ts, nil
+}
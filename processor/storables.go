@@ -6,91 +6,64 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/barnbridge/meminero/config"
-	"github.com/barnbridge/meminero/processor/storables/accounterc20transfers"
-	"github.com/barnbridge/meminero/processor/storables/dao/barn"
-	"github.com/barnbridge/meminero/processor/storables/dao/governance"
-	"github.com/barnbridge/meminero/processor/storables/erc20transfers"
-	saEvents "github.com/barnbridge/meminero/processor/storables/smartalpha/events"
-	saRewards "github.com/barnbridge/meminero/processor/storables/smartalpha/rewards"
-	saState "github.com/barnbridge/meminero/processor/storables/smartalpha/state"
-	seScrape "github.com/barnbridge/meminero/processor/storables/smartexposure/events"
-	sePools "github.com/barnbridge/meminero/processor/storables/smartexposure/pool_state"
-	seTranches "github.com/barnbridge/meminero/processor/storables/smartexposure/tranche_state"
-	syERC721 "github.com/barnbridge/meminero/processor/storables/smartyield/erc721"
-	syEvents "github.com/barnbridge/meminero/processor/storables/smartyield/events"
-	syRewards "github.com/barnbridge/meminero/processor/storables/smartyield/rewards"
-	syState "github.com/barnbridge/meminero/processor/storables/smartyield/state"
-	"github.com/barnbridge/meminero/processor/storables/tokenprices"
-	"github.com/barnbridge/meminero/processor/storables/yieldfarming"
+	"github.com/barnbridge/meminero/types"
 )
 
-// registerStorables instantiates all the storables defined via code with the requested raw data
+// registerStorables instantiates the enabled storables of the storable graph with the requested raw data, stage by stage
 // Only the storables that are registered will be executed when the Store function is called
 func (p *Processor) registerStorables() {
-	if config.Store.Storable.AccountERC20Transfers.Enabled {
-		p.storables = append(p.storables, accounterc20transfers.New(p.Block, p.state))
+	g, err := StorableGraph()
+	if err != nil {
+		logrus.Fatalf("invalid storable graph: %s", err)
 	}
 
-	if config.Store.Storable.Erc20Transfers.Enabled {
-		p.storables = append(p.storables, erc20transfers.New(p.Block, p.state))
-	}
+	p.checkTokens()
 
-	if config.Store.Storable.TokenPrices.Enabled && config.Store.Feature.ContractState.Enabled {
-		p.storables = append(p.storables, tokenprices.New(p.Block, p.state))
-	}
+	p.stages = make([][]types.Storable, len(g.Stages))
+	for i, ids := range g.Stages {
+		for _, id := range ids {
+			d := g.defs[id]
 
-	if config.Store.Storable.YieldFarming.Enabled {
-		p.storables = append(p.storables, yieldfarming.New(p.Block))
-	}
+			s := d.new(p)
+			if d.sampling != "" {
+				s = p.withSampling(d.sampling, samplingConfig(d.sampling), s)
+			}
 
-	p.registerDAO()
-	p.registerSmartYield()
-	p.registerSmartExposure()
-	p.registerSmartAlpha()
+			p.stages[i] = append(p.stages[i], s)
+			p.storables = append(p.storables, s)
+		}
+	}
 }
 
-func (p *Processor) registerDAO() {
-	if config.Store.Storable.Governance.Enabled {
-		p.storables = append(p.storables, governance.New(p.Block))
-	} else if config.Store.Storable.Barn.Enabled {
-		logrus.Fatal("governance is disabled but other storables depend on it")
+// samplingConfig returns the sampling config of the storable with the given key in the storable config
+func samplingConfig(name string) config.Sampling {
+	switch name {
+	case "tokenPrices":
+		return config.Store.Storable.TokenPrices.Sampling
+	case "smartYield":
+		return config.Store.Storable.SmartYield.Sampling
+	case "smartExposure":
+		return config.Store.Storable.SmartExposure.Sampling
+	case "smartAlpha":
+		return config.Store.Storable.SmartAlpha.Sampling
 	}
 
-	if config.Store.Storable.Barn.Enabled {
-		p.storables = append(p.storables, barn.New(p.Block))
-	} else if config.Store.Storable.Governance.Enabled {
-		logrus.Fatal("barn is disabled but other storables depend on it")
-	}
+	logrus.Fatalf("no sampling config for %s", name)
+
+	return config.Sampling{}
 }
 
-func (p *Processor) registerSmartYield() {
+// checkTokens makes sure the tokens used by the enabled products are in the tokens list
+func (p *Processor) checkTokens() {
 	if config.Store.Storable.SmartYield.Enabled {
-		if config.Store.Feature.ContractState.Enabled && (!config.Store.Storable.Erc20Transfers.Enabled || !config.Store.Storable.TokenPrices.Enabled) {
-			logrus.Fatal("could not register smartYield storables because incomplete dependencies")
-		}
-
 		for _, pool := range p.state.SmartYield.Pools {
 			if !p.state.CheckTokenExists(pool.UnderlyingAddress) {
 				logrus.Fatalf("smart yield underlying token missing from tokens list: %s (%s)", pool.UnderlyingSymbol, pool.UnderlyingAddress)
 			}
 		}
-
-		p.storables = append(p.storables, syEvents.New(p.Block, p.state))
-		p.storables = append(p.storables, syERC721.New(p.Block, p.state))
-		p.storables = append(p.storables, syRewards.New(p.Block, p.state))
-
-		if config.Store.Feature.ContractState.Enabled {
-			p.storables = append(p.storables, syState.New(p.Block, p.state))
-		}
 	}
-}
 
-func (p *Processor) registerSmartExposure() {
 	if config.Store.Storable.SmartExposure.Enabled {
-		if config.Store.Feature.ContractState.Enabled && (!config.Store.Storable.Erc20Transfers.Enabled || !config.Store.Storable.TokenPrices.Enabled) {
-			logrus.Fatal("could not register smartExposure storables because incomplete dependencies")
-		}
-
 		for _, pool := range p.state.SmartExposure.Pools {
 			if !p.state.CheckTokenExists(pool.TokenA.Address) {
 				logrus.Fatalf("smart exposure underlying token missing from tokens list: %s (%s)", pool.TokenA.Symbol, pool.TokenA.Address)
@@ -100,22 +73,9 @@ func (p *Processor) registerSmartExposure() {
 				logrus.Fatalf("smart exposure underlying token missing from tokens list: %s (%s)", pool.TokenB.Symbol, pool.TokenB.Address)
 			}
 		}
-
-		p.storables = append(p.storables, seScrape.New(p.Block, p.state))
-
-		if config.Store.Feature.ContractState.Enabled {
-			p.storables = append(p.storables, seTranches.New(p.Block, p.state))
-			p.storables = append(p.storables, sePools.New(p.Block, p.state))
-		}
 	}
-}
 
-func (p *Processor) registerSmartAlpha() {
 	if config.Store.Storable.SmartAlpha.Enabled {
-		if config.Store.Feature.ContractState.Enabled && (!config.Store.Storable.Erc20Transfers.Enabled || !config.Store.Storable.TokenPrices.Enabled) {
-			logrus.Fatal("could not register smartAlpha storables because incomplete dependencies")
-		}
-
 		for _, pool := range p.state.SmartAlpha.Pools {
 			if !p.state.CheckTokenExists(pool.PoolToken.Address) {
 				logrus.Fatalf("smart alpha underlying token missing from tokens list: %s (%s)", pool.PoolToken.Symbol, pool.PoolToken.Address)
@@ -145,15 +105,8 @@ func (p *Processor) registerSmartAlpha() {
 
 		for _, rewardPool := range p.state.SmartAlpha.RewardPools {
 			if !p.state.CheckTokenExists(rewardPool.PoolTokenAddress) {
-				logrus.Fatalf("smart alpha reward pool missing pool token from tokens list: %s (%s)", rewardPool.PoolTokenAddress)
+				logrus.Fatalf("smart alpha reward pool missing pool token from tokens list: %s (%s)", rewardPool.PoolAddress, rewardPool.PoolTokenAddress)
 			}
 		}
-
-		p.storables = append(p.storables, saEvents.New(p.Block, p.state))
-		p.storables = append(p.storables, saRewards.New(p.Block, p.state))
-
-		if config.Store.Feature.ContractState.Enabled {
-			p.storables = append(p.storables, saState.New(p.Block, p.state))
-		}
 	}
 }
@@ -22,19 +22,19 @@ type Storable struct {
 }
 
 const (
-	storableID = "boilerplate"
+	ID = "boilerplate"
 )
 
 func New(block *types.Block, state *state.Manager) *Storable {
 	return &Storable{
 		block:  block,
 		state:  state,
-		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", storableID)),
+		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", ID)),
 	}
 }
 
 func (s *Storable) ID() string {
-	return storableID
+	return ID
 }
 
 func (s *Storable) Result() interface{} {
@@ -0,0 +1,157 @@
+package contractevents
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/lacasian/ethwheels/ethgen"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/utils"
+)
+
+// Event is a decoded log of one of the configured contracts
+type Event struct {
+	Source string
+	Event  abi.Event
+	// Args are the decoded arguments by name, converted to values that encode well to json: the numbers are decimal
+	// strings, the addresses are lowercase and the bytes are hex strings
+	Args map[string]interface{}
+	Raw  gethtypes.Log
+}
+
+func (s *Storable) Execute(ctx context.Context) error {
+	sources, err := loadSources()
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range s.block.Txs {
+		for _, log := range tx.LogEntries {
+			if len(log.Topics) == 0 {
+				continue
+			}
+
+			address := utils.NormalizeAddress(log.Address.String())
+
+			for _, src := range sources {
+				if s.block.Number < src.startBlock || !src.addresses[address] {
+					continue
+				}
+
+				e, exists := src.events[log.Topics[0]]
+				if !exists {
+					continue
+				}
+
+				// the same signature can be emitted with a different set of indexed arguments (e.g. erc20 and erc721
+				// transfers), in which case the log is not the configured event
+				if len(log.Topics) != indexedArgs(e)+1 {
+					s.logger.Debugf("skipping %s log of %s in tx %s: expected %d topics, got %d", e.Name, src.name,
+						log.TxHash.String(), indexedArgs(e)+1, len(log.Topics))
+					continue
+				}
+
+				args, err := decode(src, e, log)
+				if err != nil {
+					return errors.Wrapf(err, "could not decode %s event of %s in tx %s", e.Name, src.name, log.TxHash.String())
+				}
+
+				s.processed.events = append(s.processed.events, Event{
+					Source: src.name,
+					Event:  e,
+					Args:   args,
+					Raw:    log,
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+func indexedArgs(e abi.Event) int {
+	var indexed int
+	for _, arg := range e.Inputs {
+		if arg.Indexed {
+			indexed++
+		}
+	}
+
+	return indexed
+}
+
+func decode(src *source, e abi.Event, log gethtypes.Log) (map[string]interface{}, error) {
+	decoded := make(map[string]interface{})
+	d := ethgen.Decoder{ABI: &src.abi}
+
+	err := d.UnpackLogIntoMap(decoded, e.Name, log)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make(map[string]interface{})
+	for i, arg := range e.Inputs {
+		args[argName(arg, i)] = normalize(decoded[arg.Name])
+	}
+
+	return args, nil
+}
+
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case *big.Int:
+		return x.String()
+	case common.Address:
+		return utils.NormalizeAddress(x.String())
+	case common.Hash:
+		return x.String()
+	case []byte:
+		return "0x" + hex.EncodeToString(x)
+	case bool, string, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return x
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array:
+		// fixed size bytes
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+
+			return "0x" + hex.EncodeToString(b)
+		}
+
+		fallthrough
+	case reflect.Slice:
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = normalize(rv.Index(i).Interface())
+		}
+
+		return values
+	case reflect.Struct:
+		// tuples are decoded into anonymous structs whose fields have the json tags of the components
+		values := make(map[string]interface{})
+		for i := 0; i < rv.NumField(); i++ {
+			name := rv.Type().Field(i).Tag.Get("json")
+			if name == "" {
+				name = rv.Type().Field(i).Name
+			}
+
+			values[name] = normalize(rv.Field(i).Interface())
+		}
+
+		return values
+	}
+
+	return v
+}
@@ -0,0 +1,39 @@
+package contractevents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+func (s *Storable) Rollback(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `delete from public.contract_events where included_in_block = $1`, s.block.Number)
+	if err != nil {
+		return err
+	}
+
+	if !config.Store.Storable.ContractEvents.TypedTables {
+		return nil
+	}
+
+	sources, err := loadSources()
+	if err != nil {
+		return err
+	}
+
+	for _, src := range sources {
+		for _, e := range src.events {
+			query := fmt.Sprintf(`delete from %s where included_in_block = $1`, pgx.Identifier{typedSchema, src.tableName(e)}.Sanitize())
+
+			_, err = tx.Exec(ctx, query, s.block.Number)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
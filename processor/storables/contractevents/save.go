@@ -0,0 +1,108 @@
+package contractevents
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/utils"
+)
+
+func (s *Storable) SaveToDatabase(ctx context.Context, tx pgx.Tx) error {
+	if len(s.processed.events) == 0 {
+		return nil
+	}
+
+	var rows [][]interface{}
+	for _, e := range s.processed.events {
+		rows = append(rows, []interface{}{
+			e.Source,
+			utils.NormalizeAddress(e.Raw.Address.String()),
+			e.Event.Name,
+			e.Event.Sig,
+			e.Args,
+			s.block.BlockCreationTime,
+			s.block.Number,
+			utils.NormalizeAddress(e.Raw.TxHash.String()),
+			e.Raw.TxIndex,
+			e.Raw.Index,
+		})
+	}
+
+	_, err := tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"contract_events"},
+		[]string{"source", "contract_address", "event_name", "event_signature", "args", "block_timestamp", "included_in_block", "tx_hash", "tx_index", "log_index"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not store contract events")
+	}
+
+	if config.Store.Storable.ContractEvents.TypedTables {
+		return s.saveTyped(ctx, tx)
+	}
+
+	return nil
+}
+
+func (s *Storable) saveTyped(ctx context.Context, tx pgx.Tx) error {
+	sources, err := loadSources()
+	if err != nil {
+		return err
+	}
+
+	rows := make(map[string][][]interface{})
+	cols := make(map[string][]column)
+
+	for _, e := range s.processed.events {
+		var src *source
+		for _, x := range sources {
+			if x.name == e.Source {
+				src = x
+			}
+		}
+
+		table := src.tableName(e.Event)
+		if _, exists := cols[table]; !exists {
+			cols[table] = columns(e.Event)
+		}
+
+		var row []interface{}
+		for _, c := range cols[table] {
+			v, err := typedValue(c, e.Args[c.arg])
+			if err != nil {
+				return errors.Wrapf(err, "could not convert %s argument of %s event", c.arg, e.Event.Name)
+			}
+
+			row = append(row, v)
+		}
+
+		row = append(row,
+			utils.NormalizeAddress(e.Raw.Address.String()),
+			s.block.BlockCreationTime,
+			s.block.Number,
+			utils.NormalizeAddress(e.Raw.TxHash.String()),
+			e.Raw.TxIndex,
+			e.Raw.Index,
+		)
+
+		rows[table] = append(rows[table], row)
+	}
+
+	for table, r := range rows {
+		var names []string
+		for _, c := range cols[table] {
+			names = append(names, c.name)
+		}
+
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{typedSchema, table}, append(names, metadataColumns...), pgx.CopyFromRows(r))
+		if err != nil {
+			return errors.Wrapf(err, "could not store events in %s", table)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package contractevents
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/utils"
+)
+
+// source is a ContractEventsSource from the config, with its ABI loaded
+type source struct {
+	name       string
+	addresses  map[string]bool
+	abi        abi.ABI
+	events     map[common.Hash]abi.Event
+	startBlock int64
+}
+
+var (
+	sourcesOnce sync.Once
+	sources     []*source
+	sourcesErr  error
+
+	wordBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	invalidChars = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+// loadSources reads the sources from the config once; the ABI files don't change at runtime
+func loadSources() ([]*source, error) {
+	sourcesOnce.Do(func() {
+		sources, sourcesErr = buildSources(config.Store.Storable.ContractEvents.Contracts)
+	})
+
+	return sources, sourcesErr
+}
+
+func buildSources(contracts []config.ContractEventsSource) ([]*source, error) {
+	var result []*source
+	names := make(map[string]bool)
+
+	for _, c := range contracts {
+		if c.Name == "" {
+			return nil, errors.New("contract events source without a name")
+		}
+
+		name := identifier(c.Name)
+		if names[name] {
+			return nil, errors.Errorf("contract events source %s is declared twice", c.Name)
+		}
+		names[name] = true
+
+		if len(c.Addresses) == 0 {
+			return nil, errors.Errorf("contract events source %s has no addresses", c.Name)
+		}
+
+		f, err := os.Open(filepath.Join(config.Store.EthTypes.AbiFolder, c.ABI))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not open the abi of contract events source %s", c.Name)
+		}
+
+		a, err := abi.JSON(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse the abi of contract events source %s", c.Name)
+		}
+
+		s := &source{
+			name:       name,
+			addresses:  make(map[string]bool),
+			abi:        a,
+			events:     make(map[common.Hash]abi.Event),
+			startBlock: c.StartBlock,
+		}
+
+		for _, addr := range c.Addresses {
+			s.addresses[utils.NormalizeAddress(strings.TrimSpace(addr))] = true
+		}
+
+		if len(c.Events) == 0 {
+			for _, e := range a.Events {
+				if !e.Anonymous {
+					s.events[e.ID] = e
+				}
+			}
+		}
+
+		for _, name := range c.Events {
+			e, exists := a.Events[name]
+			if !exists {
+				return nil, errors.Errorf("event %s of contract events source %s is not in its abi", name, c.Name)
+			}
+
+			if e.Anonymous {
+				return nil, errors.Errorf("event %s of contract events source %s is anonymous and can't be matched", name, c.Name)
+			}
+
+			s.events[e.ID] = e
+		}
+
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+// identifier turns a name from the config or the ABI into a snake case postgres identifier
+func identifier(name string) string {
+	name = strings.ToLower(wordBoundary.ReplaceAllString(name, "${1}_${2}"))
+
+	return strings.Trim(invalidChars.ReplaceAllString(name, "_"), "_")
+}
+
+// tableName is the name of the typed table of an event in the contract_events schema
+func (s *source) tableName(e abi.Event) string {
+	return fmt.Sprintf("%s_%s", s.name, identifier(e.Name))
+}
+
+// argName is the key of an event argument in the jsonb args; the unnamed arguments are named after their position
+func argName(arg abi.Argument, i int) string {
+	if arg.Name == "" {
+		return fmt.Sprintf("arg%d", i)
+	}
+
+	return arg.Name
+}
@@ -0,0 +1,36 @@
+package contractevents
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/types"
+)
+
+type Storable struct {
+	block *types.Block
+
+	logger *logrus.Entry
+
+	processed struct {
+		events []Event
+	}
+}
+
+const ID = "contractEvents"
+
+func New(block *types.Block) *Storable {
+	return &Storable{
+		block:  block,
+		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", ID)),
+	}
+}
+
+func (s *Storable) ID() string {
+	return ID
+}
+
+func (s *Storable) Result() interface{} {
+	return s.processed
+}
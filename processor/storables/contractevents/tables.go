@@ -0,0 +1,156 @@
+package contractevents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+const typedSchema = "contract_events"
+
+// metadataColumns are the columns of the typed tables that don't come from the event arguments
+var metadataColumns = []string{"contract_address", "block_timestamp", "included_in_block", "tx_hash", "tx_index", "log_index"}
+
+type column struct {
+	name    string
+	sqlType string
+	arg     string
+	abiType abi.Type
+}
+
+// Init checks the configured sources and creates the typed tables of their events if they are enabled
+// it's meant to be called at startup so a bad config fails right away instead of on the first block
+func Init(ctx context.Context, db *pgxpool.Pool) error {
+	sources, err := loadSources()
+	if err != nil {
+		return err
+	}
+
+	if !config.Store.Storable.ContractEvents.TypedTables {
+		return nil
+	}
+
+	_, err = db.Exec(ctx, fmt.Sprintf("create schema if not exists %s;", typedSchema))
+	if err != nil {
+		return errors.Wrap(err, "could not create contract events schema")
+	}
+
+	for _, s := range sources {
+		for _, e := range s.events {
+			err = createTable(ctx, db, s, e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func createTable(ctx context.Context, db *pgxpool.Pool, s *source, e abi.Event) error {
+	table := s.tableName(e)
+	// postgres truncates the identifiers to 63 characters, which would mix up the tables (or their indexes) of similar
+	// events
+	if len(table+"_block_idx") > 63 {
+		return errors.Errorf("typed table name %s is too long; use a shorter name for contract events source %s", table, s.name)
+	}
+
+	var defs []string
+	for _, c := range columns(e) {
+		defs = append(defs, fmt.Sprintf("%s %s", pgx.Identifier{c.name}.Sanitize(), c.sqlType))
+	}
+
+	defs = append(defs,
+		"contract_address text not null",
+		"block_timestamp bigint not null",
+		"included_in_block bigint not null",
+		"tx_hash text not null",
+		"tx_index integer not null",
+		"log_index integer not null",
+		"created_at timestamp default now()",
+	)
+
+	query := fmt.Sprintf(`
+		create table if not exists %s (%s);
+		create index if not exists %s on %s (included_in_block);
+	`,
+		pgx.Identifier{typedSchema, table}.Sanitize(),
+		strings.Join(defs, ", "),
+		pgx.Identifier{table + "_block_idx"}.Sanitize(),
+		pgx.Identifier{typedSchema, table}.Sanitize(),
+	)
+
+	_, err := db.Exec(ctx, query)
+	if err != nil {
+		return errors.Wrapf(err, "could not create typed table %s", table)
+	}
+
+	return nil
+}
+
+// columns maps the arguments of an event to the columns of its typed table
+func columns(e abi.Event) []column {
+	reserved := make(map[string]bool)
+	for _, c := range metadataColumns {
+		reserved[c] = true
+	}
+	reserved["created_at"] = true
+
+	var result []column
+	for i, arg := range e.Inputs {
+		name := identifier(argName(arg, i))
+		if reserved[name] || name == "" {
+			name = fmt.Sprintf("arg_%s", name)
+		}
+
+		// different arguments can still end up with the same identifier (e.g. fooBar and foo_bar)
+		for base, n := name, 2; reserved[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		reserved[name] = true
+
+		result = append(result, column{
+			name:    name,
+			sqlType: sqlType(arg.Type),
+			arg:     argName(arg, i),
+			abiType: arg.Type,
+		})
+	}
+
+	return result
+}
+
+func sqlType(t abi.Type) string {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		return "numeric(78)"
+	case abi.BoolTy:
+		return "boolean"
+	case abi.AddressTy, abi.StringTy, abi.BytesTy, abi.FixedBytesTy, abi.HashTy:
+		return "text"
+	}
+
+	return "jsonb"
+}
+
+// typedValue converts a normalized argument to the type of its column
+func typedValue(c column, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch c.abiType.T {
+	case abi.IntTy, abi.UintTy:
+		return decimal.NewFromString(fmt.Sprint(v))
+	}
+
+	return v, nil
+}
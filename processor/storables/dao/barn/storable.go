@@ -22,17 +22,17 @@ type Storable struct {
 	}
 }
 
-const storableID = "dao.barn"
+const ID = "dao.barn"
 
 func New(block *types.Block) *Storable {
 	return &Storable{
 		block:  block,
-		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", storableID)),
+		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", ID)),
 	}
 }
 
 func (s *Storable) ID() string {
-	return storableID
+	return ID
 }
 
 func (s *Storable) Result() interface{} {
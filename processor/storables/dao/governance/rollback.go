@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v4"
+
+	"github.com/barnbridge/meminero/notifications"
 )
 
 func (s *Storable) Rollback(ctx context.Context, tx pgx.Tx) error {
@@ -22,5 +24,9 @@ func (s *Storable) Rollback(ctx context.Context, tx pgx.Tx) error {
 	}
 
 	err = br.Close()
-	return err
+	if err != nil {
+		return err
+	}
+
+	return notifications.RollbackWithTx(ctx, tx, s.block.Number, notifications.GovernanceTypes)
 }
@@ -172,7 +172,9 @@ func (s *Storable) storeAbrogationProposals(ctx context.Context, tx pgx.Tx) erro
 	}
 
 	if config.Store.Storable.Governance.Notifications {
-		ctx, _ := context.WithTimeout(context.Background(), time.Second*2)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+		defer cancel()
+
 		err = notifications.ExecuteJobsWithTx(ctx, tx, jobs...)
 		if err != nil && err != context.DeadlineExceeded {
 			return errors.Wrap(err, "could not execute notification jobs")
@@ -26,17 +26,17 @@ type Storable struct {
 	}
 }
 
-const storableID = "dao.governance"
+const ID = "dao.governance"
 
 func New(block *types.Block) *Storable {
 	return &Storable{
 		block:  block,
-		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", storableID)),
+		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", ID)),
 	}
 }
 
 func (s *Storable) ID() string {
-	return storableID
+	return ID
 }
 
 func (s *Storable) Result() interface{} {
@@ -15,7 +15,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 			if s.state.SmartAlpha.RewardPoolByAddress(log.Address.String()) != nil {
 				err := s.processRewardPoolEvent(log)
 				if err != nil {
-					return errors.Wrapf(err, "could not process reward pool event %s")
+					return errors.Wrapf(err, "could not process reward pool event %s", log.TxHash.String())
 				}
 			}
 		}
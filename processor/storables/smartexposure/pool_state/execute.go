@@ -18,14 +18,17 @@ import (
 
 func (s *Storable) Execute(ctx context.Context) error {
 	s.processed.poolStates = make(map[string]PoolState)
-	tokens, err := smartexposure.BuildTokensSliceForSE(s.state)
-	if err != nil {
-		return err
-	}
+	s.processed.tokenPrices = s.prices
+	if s.processed.tokenPrices == nil {
+		tokens, err := smartexposure.BuildTokensSliceForSE(s.state)
+		if err != nil {
+			return err
+		}
 
-	s.processed.tokenPrices, err = tokenprices.GetTokensPrices(ctx, tokens, s.block.Number)
-	if err != nil {
-		return err
+		s.processed.tokenPrices, err = tokenprices.GetTokensPrices(ctx, tokens, s.block.Number)
+		if err != nil {
+			return err
+		}
 	}
 
 	wg, _ := errgroup.WithContext(ctx)
@@ -6,6 +6,7 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 
+	"github.com/barnbridge/meminero/processor/storables/tokenprices"
 	"github.com/barnbridge/meminero/state"
 	"github.com/barnbridge/meminero/types"
 )
@@ -16,26 +17,37 @@ type Storable struct {
 	state  *state.Manager
 	logger *logrus.Entry
 
+	// prices are the token prices computed by the tokenPrices storable; they are fetched by the storable itself if
+	// that one did not run on this block
+	prices tokenprices.Prices
+
 	processed struct {
 		poolStates  map[string]PoolState
 		tokenPrices map[string]map[string]decimal.Decimal
 	}
 }
 
-const storableID = "smartExposure.poolState"
+const ID = "smartExposure.poolState"
 
 func New(block *types.Block, state *state.Manager) *Storable {
 	return &Storable{
 		block:  block,
 		state:  state,
-		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", storableID)),
+		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", ID)),
 	}
 }
 
 func (s *Storable) ID() string {
-	return storableID
+	return ID
 }
 
 func (s *Storable) Result() interface{} {
 	return s.processed
 }
+
+// Consume takes the token prices from the results of the tokenPrices storable
+func (s *Storable) Consume(results map[string]interface{}) {
+	if prices, ok := results[tokenprices.ID].(tokenprices.Prices); ok {
+		s.prices = prices
+	}
+}
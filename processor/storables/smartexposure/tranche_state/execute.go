@@ -20,14 +20,17 @@ import (
 
 func (s *Storable) Execute(ctx context.Context) error {
 	s.processed.trancheState = make(map[string]TrancheState)
-	tokens, err := smartexposure.BuildTokensSliceForSE(s.state)
-	if err != nil {
-		return err
-	}
+	s.processed.tokenPrices = s.prices
+	if s.processed.tokenPrices == nil {
+		tokens, err := smartexposure.BuildTokensSliceForSE(s.state)
+		if err != nil {
+			return err
+		}
 
-	s.processed.tokenPrices, err = tokenprices.GetTokensPrices(ctx, tokens, s.block.Number)
-	if err != nil {
-		return err
+		s.processed.tokenPrices, err = tokenprices.GetTokensPrices(ctx, tokens, s.block.Number)
+		if err != nil {
+			return err
+		}
 	}
 
 	wg, _ := errgroup.WithContext(ctx)
@@ -77,7 +80,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 		})
 	}
 
-	err = wg.Wait()
+	err := wg.Wait()
 	if err != nil {
 		return errors.Wrap(err, "could not get data from chain")
 	}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v4"
+
+	"github.com/barnbridge/meminero/notifications"
 )
 
 func (s *Storable) Rollback(ctx context.Context, tx pgx.Tx) error {
@@ -21,5 +23,10 @@ func (s *Storable) Rollback(ctx context.Context, tx pgx.Tx) error {
 		return err
 	}
 
-	return br.Close()
+	err = br.Close()
+	if err != nil {
+		return err
+	}
+
+	return notifications.RollbackWithTx(ctx, tx, s.block.Number, notifications.SmartYieldTypes)
 }
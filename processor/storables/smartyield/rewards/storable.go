@@ -29,19 +29,19 @@ type Storable struct {
 	}
 }
 
-const storableID = "smartYield.rewards"
+const ID = "smartYield.rewards"
 
 func New(block *types.Block, state *state.Manager) *Storable {
 	return &Storable{
 		block:     block,
 		state:     state,
-		logger:    logrus.WithField("module", fmt.Sprintf("storable(%s)", storableID)),
+		logger:    logrus.WithField("module", fmt.Sprintf("storable(%s)", ID)),
 		factories: strings.Split(config.Store.Storable.SmartYield.Rewards.Factories, ","),
 	}
 }
 
 func (s *Storable) ID() string {
-	return storableID
+	return ID
 }
 
 func (s Storable) Result() interface{} {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"math"
 	"math/big"
-	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -41,7 +40,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 			subwg, _ := errgroup.WithContext(ctx1)
 			subwg.Go(func() error {
 				err := eth.CallContractFunction(syAbi, p.PoolAddress, "underlyingTotal", []interface{}{}, &underlyingTotal, s.block.Number)()
-				if err != nil && strings.Contains(err.Error(), "execution reverted") {
+				if eth.IsReverted(err) {
 					underlyingTotal = big.NewInt(0)
 					return nil
 				}
@@ -50,7 +49,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 			})
 			subwg.Go(func() error {
 				err := eth.CallContractFunction(syAbi, p.PoolAddress, "underlyingJuniors", []interface{}{}, &underlyingJuniors, s.block.Number)()
-				if err != nil && strings.Contains(err.Error(), "execution reverted") {
+				if eth.IsReverted(err) {
 					underlyingJuniors = big.NewInt(0)
 					return nil
 				}
@@ -59,7 +58,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 			})
 			subwg.Go(func() error {
 				err := eth.CallContractFunction(syAbi, p.PoolAddress, "price", []interface{}{}, &jtokenPrice, s.block.Number)()
-				if err != nil && strings.Contains(err.Error(), "execution reverted") {
+				if eth.IsReverted(err) {
 					jtokenPrice = big.NewInt(0)
 					return nil
 				}
@@ -71,7 +70,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 				var maxBondDailyRate = big.NewInt(0)
 
 				err := eth.CallContractFunction(syAbi, p.PoolAddress, "maxBondDailyRate", []interface{}{}, &maxBondDailyRate, s.block.Number)()
-				if err != nil && !strings.Contains(err.Error(), "Reverted") && !strings.Contains(err.Error(), "execution reverted") {
+				if err != nil && !eth.IsReverted(err) {
 					return errors.Wrap(err, "could not get maxBondDailyRate")
 				}
 
@@ -90,7 +89,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 					var rate = big.NewInt(0)
 
 					err := eth.CallContractFunction(controllerAbi, p.ControllerAddress, "spotDailySupplyRateProvider", []interface{}{}, &rate, s.block.Number)()
-					if err != nil && !strings.Contains(err.Error(), "Reverted") {
+					if err != nil && !eth.IsReverted(err) {
 						return errors.Wrap(err, "could not get originator apy")
 					}
 
@@ -108,7 +107,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 					var rate = big.NewInt(0)
 
 					err := eth.CallContractFunction(controllerAbi, p.ControllerAddress, "spotDailyRate", []interface{}{}, &rate, s.block.Number)()
-					if err != nil && !strings.Contains(err.Error(), "Reverted") {
+					if err != nil && !eth.IsReverted(err) {
 						return errors.Wrap(err, "could not get originator NET apy")
 					}
 
@@ -126,7 +125,7 @@ func (s *Storable) Execute(ctx context.Context) error {
 					var rate = big.NewInt(0)
 
 					err := eth.CallContractFunction(controllerAbi, p.ControllerAddress, "spotDailySupplyRateProvider", []interface{}{}, &rate, s.block.Number)()
-					if err != nil && !strings.Contains(err.Error(), "Reverted") {
+					if err != nil && !eth.IsReverted(err) {
 						return errors.Wrap(err, "could not get originator apy")
 					}
 
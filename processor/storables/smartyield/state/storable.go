@@ -22,18 +22,18 @@ type Storable struct {
 	processedMu sync.Mutex
 }
 
-const storableID = "smartYield.state"
+const ID = "smartYield.state"
 
 func New(block *globalTypes.Block, state *state.Manager) *Storable {
 	return &Storable{
 		block:  block,
 		state:  state,
-		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", storableID)),
+		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", ID)),
 	}
 }
 
 func (s *Storable) ID() string {
-	return storableID
+	return ID
 }
 
 func (s *Storable) Result() interface{} {
@@ -2,7 +2,6 @@ package tokenprices
 
 import (
 	"context"
-	"math/big"
 	"strings"
 	"sync"
 
@@ -10,18 +9,21 @@ import (
 	"github.com/shopspring/decimal"
 	"golang.org/x/sync/errgroup"
 
-	"github.com/barnbridge/meminero/eth"
-	"github.com/barnbridge/meminero/ethtypes"
 	"github.com/barnbridge/meminero/types"
-	"github.com/barnbridge/meminero/utils"
 )
 
+type hopCall struct {
+	provider Provider
+	hop      types.PriceProvider
+}
+
 func GetTokensPrices(ctx context.Context, tokens map[string]types.Token, blockNumber int64) (map[string]map[string]decimal.Decimal, error) {
-	var calls = make(map[string]types.PriceProvider)
+	var calls = make(map[string]hopCall)
 	for _, t := range tokens {
 		for _, v := range t.Prices {
-			if v.Provider != "chainlink" {
-				return nil, errors.New("invalid provider")
+			provider, exists := providers[v.Provider]
+			if !exists {
+				return nil, errors.Errorf("invalid provider %s", v.Provider)
 			}
 
 			if blockNumber < v.StartAtBlock {
@@ -29,7 +31,7 @@ func GetTokensPrices(ctx context.Context, tokens map[string]types.Token, blockNu
 			}
 
 			for _, p := range v.Path {
-				calls[utils.NormalizeAddress(p.Address)] = p
+				calls[hopKey(v.Provider, p)] = hopCall{provider: provider, hop: p}
 			}
 		}
 	}
@@ -38,18 +40,16 @@ func GetTokensPrices(ctx context.Context, tokens map[string]types.Token, blockNu
 	var mu = &sync.Mutex{}
 	results := make(map[string]decimal.Decimal)
 
-	for _, c := range calls {
-		c := c
+	for key, c := range calls {
+		key, c := key, c
 		wg.Go(func() error {
-			var price *big.Int
-			err := eth.CallContractFunction(*ethtypes.ETHAggregator.ABI, c.Address, "latestAnswer", []interface{}{}, &price, blockNumber)()
+			price, err := c.provider.Price(c.hop, blockNumber)
 			if err != nil {
-				return errors.Wrapf(err, "could not call latestAnswer on contract(%s)", c.Address)
+				return err
 			}
 
 			mu.Lock()
-			priceScaled := decimal.NewFromBigInt(price, -int32(c.Decimals))
-			results[utils.NormalizeAddress(c.Address)] = priceScaled
+			results[key] = price
 			mu.Unlock()
 
 			return nil
@@ -71,8 +71,12 @@ func GetTokensPrices(ctx context.Context, tokens map[string]types.Token, blockNu
 				price := decimal.NewFromInt(1)
 
 				for _, p := range v.Path {
-					res := results[utils.NormalizeAddress(p.Address)]
+					res := results[hopKey(v.Provider, p)]
 					if p.Reverse {
+						if res.IsZero() {
+							return nil, errors.Errorf("could not reverse zero price of %s hop %s for token %s", v.Provider, p.Address, t.Address)
+						}
+
 						res = decimal.NewFromInt(1).Div(res)
 					}
 
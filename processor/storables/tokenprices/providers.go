@@ -0,0 +1,171 @@
+package tokenprices
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/barnbridge/meminero/eth"
+	"github.com/barnbridge/meminero/ethtypes"
+	"github.com/barnbridge/meminero/types"
+	"github.com/barnbridge/meminero/utils"
+)
+
+// defaultTWAPInterval is used for uniswap v3 hops that don't specify a twapInterval
+const defaultTWAPInterval = 1800
+
+// Provider returns the price of a single hop of a price path at the given block
+type Provider interface {
+	Price(hop types.PriceProvider, blockNumber int64) (decimal.Decimal, error)
+}
+
+var providers = map[string]Provider{
+	types.PriceProviderChainlink: chainlink{},
+	types.PriceProviderUniswapV2: uniswapV2{},
+	types.PriceProviderUniswapV3: uniswapV3{},
+	types.PriceProviderCurve:     curve{},
+	types.PriceProviderFixed:     fixed{},
+}
+
+// hopKey identifies the calls that can be shared between tokens; the reverse flag is applied afterwards
+func hopKey(provider string, hop types.PriceProvider) string {
+	value := ""
+	if hop.Value != nil {
+		value = hop.Value.String()
+	}
+
+	return fmt.Sprintf("%s:%s:%d:%d:%d:%d:%s", provider, utils.NormalizeAddress(hop.Address), hop.Decimals,
+		hop.Token0Decimals, hop.Token1Decimals, hop.TWAPInterval, value)
+}
+
+type chainlink struct{}
+
+func (chainlink) Price(hop types.PriceProvider, blockNumber int64) (decimal.Decimal, error) {
+	var price *big.Int
+	err := eth.CallContractFunction(*ethtypes.ETHAggregator.ABI, hop.Address, "latestAnswer", []interface{}{}, &price, blockNumber)()
+	if err != nil {
+		return decimal.Zero, errors.Wrapf(err, "could not call latestAnswer on contract(%s)", hop.Address)
+	}
+
+	return decimal.NewFromBigInt(price, -int32(hop.Decimals)), nil
+}
+
+// uniswapV2 prices token0 in token1 using the pair's reserves
+type uniswapV2 struct{}
+
+func (uniswapV2) Price(hop types.PriceProvider, blockNumber int64) (decimal.Decimal, error) {
+	var reserves struct {
+		Reserve0           *big.Int
+		Reserve1           *big.Int
+		BlockTimestampLast uint32
+	}
+	err := eth.CallContractFunction(*ethtypes.UniswapV2Pair.ABI, hop.Address, "getReserves", []interface{}{}, &reserves, blockNumber)()
+	if err != nil {
+		return decimal.Zero, errors.Wrapf(err, "could not call getReserves on contract(%s)", hop.Address)
+	}
+
+	if reserves.Reserve0.Sign() == 0 || reserves.Reserve1.Sign() == 0 {
+		return decimal.Zero, errors.Errorf("pair(%s) has no liquidity", hop.Address)
+	}
+
+	reserve0 := decimal.NewFromBigInt(reserves.Reserve0, -int32(hop.Token0Decimals))
+	reserve1 := decimal.NewFromBigInt(reserves.Reserve1, -int32(hop.Token1Decimals))
+
+	return reserve1.Div(reserve0), nil
+}
+
+// uniswapV3 prices token0 in token1 using the time weighted average tick of the pool over the last twapInterval seconds
+type uniswapV3 struct{}
+
+func (uniswapV3) Price(hop types.PriceProvider, blockNumber int64) (decimal.Decimal, error) {
+	interval := hop.TWAPInterval
+	if interval == 0 {
+		interval = defaultTWAPInterval
+	}
+
+	var observations struct {
+		TickCumulatives                    []*big.Int
+		SecondsPerLiquidityCumulativeX128s []*big.Int
+	}
+	err := eth.CallContractFunction(*ethtypes.UniswapV3Pool.ABI, hop.Address, "observe", []interface{}{[]uint32{interval, 0}}, &observations, blockNumber)()
+	if err != nil {
+		return decimal.Zero, errors.Wrapf(err, "could not call observe on contract(%s)", hop.Address)
+	}
+
+	if len(observations.TickCumulatives) != 2 {
+		return decimal.Zero, errors.Errorf("unexpected number of observations from contract(%s)", hop.Address)
+	}
+
+	delta := new(big.Int).Sub(observations.TickCumulatives[1], observations.TickCumulatives[0]).Int64()
+
+	// same rounding as the uniswap OracleLibrary: towards negative infinity
+	tick := delta / int64(interval)
+	if delta < 0 && delta%int64(interval) != 0 {
+		tick--
+	}
+
+	price, err := tickPrice(tick)
+	if err != nil {
+		return decimal.Zero, errors.Wrapf(err, "could not compute price of contract(%s)", hop.Address)
+	}
+
+	return price.Shift(int32(hop.Token0Decimals - hop.Token1Decimals)), nil
+}
+
+// tickPrecision is the precision in bits of the tick price computation; ticks are limited to +-887272, so 1.0001^tick
+// stays within 2^+-128 and keeps way more significant digits than the prices are stored with
+const tickPrecision = 256
+
+// tickPrice computes 1.0001^tick by squaring, without going through float64
+func tickPrice(tick int64) (decimal.Decimal, error) {
+	base, _, err := big.ParseFloat("1.0001", 10, tickPrecision, big.ToNearestEven)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	exp := tick
+	if exp < 0 {
+		exp = -exp
+	}
+
+	result := new(big.Float).SetPrec(tickPrecision).SetInt64(1)
+	for ; exp > 0; exp >>= 1 {
+		if exp&1 == 1 {
+			result.Mul(result, base)
+		}
+
+		base.Mul(base, base)
+	}
+
+	if tick < 0 {
+		result.Quo(new(big.Float).SetPrec(tickPrecision).SetInt64(1), result)
+	}
+
+	return decimal.NewFromString(result.Text('e', 40))
+}
+
+// curve prices the pool's lp token in the underlying asset
+type curve struct{}
+
+func (curve) Price(hop types.PriceProvider, blockNumber int64) (decimal.Decimal, error) {
+	var price *big.Int
+	err := eth.CallContractFunction(*ethtypes.CurvePool.ABI, hop.Address, "get_virtual_price", []interface{}{}, &price, blockNumber)()
+	if err != nil {
+		return decimal.Zero, errors.Wrapf(err, "could not call get_virtual_price on contract(%s)", hop.Address)
+	}
+
+	return decimal.NewFromBigInt(price, -int32(hop.Decimals)), nil
+}
+
+// fixed returns a constant price; used for pegged assets that have no on-chain feed
+type fixed struct{}
+
+func (fixed) Price(hop types.PriceProvider, _ int64) (decimal.Decimal, error) {
+	if hop.Value == nil {
+		return decimal.Zero, errors.New("fixed price provider is missing a value")
+	}
+
+	return *hop.Value, nil
+}
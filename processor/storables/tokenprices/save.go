@@ -17,7 +17,6 @@ func (s *Storable) SaveToDatabase(ctx context.Context, tx pgx.Tx) error {
 	for tokenAddress, prices := range s.processed.prices {
 		for quoteAsset, price := range prices {
 			token := s.state.GetTokenByAddress(tokenAddress)
-			price, _ := price.Float64()
 			rows = append(rows, []interface{}{
 				tokenAddress,
 				token.Symbol,
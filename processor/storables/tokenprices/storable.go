@@ -21,20 +21,23 @@ type Storable struct {
 	}
 }
 
-const storableID = "tokenPrices"
+const ID = "tokenPrices"
+
+// Prices is the Result of the storable: the price of every token by quote asset, by token address
+type Prices map[string]map[string]decimal.Decimal
 
 func New(block *types.Block, state *state.Manager) *Storable {
 	return &Storable{
 		block:  block,
 		state:  state,
-		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", storableID)),
+		logger: logrus.WithField("module", fmt.Sprintf("storable(%s)", ID)),
 	}
 }
 
 func (s *Storable) ID() string {
-	return storableID
+	return ID
 }
 
 func (s *Storable) Result() interface{} {
-	return s.processed
+	return Prices(s.processed.prices)
 }
@@ -0,0 +1,97 @@
+package rawstore
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/types"
+	"github.com/barnbridge/meminero/utils"
+)
+
+// blocksPerFolder keeps the number of files in a folder manageable
+const blocksPerFolder = 10000
+
+// Disk keeps every block in a gzipped json file: <path>/<number / 10000>/<number>_<hash>.json.gz
+type Disk struct {
+	path string
+}
+
+func NewDisk(path string) (*Disk, error) {
+	if path == "" {
+		return nil, errors.New("the disk raw store needs a path")
+	}
+
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create raw store folder")
+	}
+
+	return &Disk{path: path}, nil
+}
+
+func (d *Disk) file(number int64, hash string) string {
+	return filepath.Join(d.path, fmt.Sprintf("%d", number/blocksPerFolder), fmt.Sprintf("%d_%s.json.gz", number, normalizeHash(hash)))
+}
+
+func (d *Disk) Save(ctx context.Context, raw *types.RawData) error {
+	number, err := utils.HexStrToBigInt(raw.Block.Number)
+	if err != nil {
+		return errors.Wrap(err, "could not decode block number")
+	}
+
+	data, err := encode(raw)
+	if err != nil {
+		return err
+	}
+
+	file := d.file(number.Int64(), raw.Block.Hash)
+
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return errors.Wrap(err, "could not create raw store folder")
+	}
+
+	// the file is written next to its final path and renamed, so a reader never sees a partial file
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "could not create raw block file")
+	}
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "could not write raw block file")
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "could not write raw block file")
+	}
+
+	err = os.Rename(tmp.Name(), file)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "could not write raw block file")
+	}
+
+	return nil
+}
+
+func (d *Disk) Get(ctx context.Context, number int64, hash string) (*types.RawData, error) {
+	data, err := ioutil.ReadFile(d.file(number, hash))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read raw block file")
+	}
+
+	return decode(data)
+}
@@ -0,0 +1,57 @@
+package rawstore
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/types"
+	"github.com/barnbridge/meminero/utils"
+)
+
+// Postgres keeps the gzipped json of every block in public.raw_blocks
+type Postgres struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgres(db *pgxpool.Pool) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Save(ctx context.Context, raw *types.RawData) error {
+	number, err := utils.HexStrToBigInt(raw.Block.Number)
+	if err != nil {
+		return errors.Wrap(err, "could not decode block number")
+	}
+
+	data, err := encode(raw)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(ctx, `
+		insert into public.raw_blocks (number, block_hash, data) values ($1, $2, $3)
+		on conflict (number, block_hash) do nothing
+	`, number.Int64(), normalizeHash(raw.Block.Hash), data)
+	if err != nil {
+		return errors.Wrap(err, "could not save raw block")
+	}
+
+	return nil
+}
+
+func (p *Postgres) Get(ctx context.Context, number int64, hash string) (*types.RawData, error) {
+	var data []byte
+
+	err := p.db.QueryRow(ctx, `select data from public.raw_blocks where number = $1 and block_hash = $2`, number, normalizeHash(hash)).Scan(&data)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get raw block")
+	}
+
+	return decode(data)
+}
@@ -0,0 +1,101 @@
+package rawstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/types"
+	"github.com/barnbridge/meminero/utils"
+)
+
+const (
+	BackendDisk     = "disk"
+	BackendPostgres = "postgres"
+)
+
+// Store keeps the raw data of the scraped blocks (the block with its transactions and the receipts), so they can be
+// processed again without the node; every version of a block is kept under its hash
+type Store interface {
+	Save(ctx context.Context, raw *types.RawData) error
+	// Get returns the raw data of the block with the given number and hash, or nil if it was not stored
+	Get(ctx context.Context, number int64, hash string) (*types.RawData, error)
+}
+
+// New opens the raw data store selected in config; the postgres backend needs a database connection
+func New(db *pgxpool.Pool) (Store, error) {
+	switch config.Store.RawStore.Backend {
+	case "", BackendDisk:
+		return NewDisk(config.Store.RawStore.Path)
+	case BackendPostgres:
+		if db == nil {
+			return nil, errors.New("the postgres raw store needs a database connection")
+		}
+
+		return NewPostgres(db), nil
+	}
+
+	return nil, errors.Errorf("unknown raw store backend: %s", config.Store.RawStore.Backend)
+}
+
+// CheckConfig fails if the raw store is enabled together with `feature.log-scraping`: the blocks scraped from the logs
+// only contain the logs of the filter, so there would be nothing to keep or reprocess
+func CheckConfig() error {
+	if config.Store.RawStore.Enabled && config.Store.Feature.LogScraping {
+		return errors.New("raw-store.enabled can't be used with feature.log-scraping")
+	}
+
+	return nil
+}
+
+// normalizeHash keeps the hashes in the same format as public.blocks
+func normalizeHash(hash string) string {
+	return strings.ToLower(utils.Trim0x(hash))
+}
+
+func encode(raw *types.RawData) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+
+	err := json.NewEncoder(w).Encode(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode raw data")
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not compress raw data")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decode(data []byte) (*types.RawData, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decompress raw data")
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decompress raw data")
+	}
+
+	var raw types.RawData
+
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode raw data")
+	}
+
+	return &raw, nil
+}
@@ -0,0 +1,215 @@
+package scraper
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	web3types "github.com/alethio/web3-go/types"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/ethtypes"
+	"github.com/barnbridge/meminero/types"
+	"github.com/barnbridge/meminero/utils"
+)
+
+// ExecLogs scrapes a block in log mode: instead of getting all the transactions and their receipts, it only gets
+// the block header and the logs matching the filter
+// It:
+// - scrapes the header using eth_getBlockByNumber (without transactions)
+// - scrapes the logs of the block using eth_getLogs with a blockHash filter, so they are guaranteed to belong to the header
+func (s *Scraper) ExecLogs(block int64, filter types.LogFilter) (*types.RawData, error) {
+	var log = s.logger.WithField("block", block)
+
+	log.Debug("getting block header")
+	start := time.Now()
+	header, err := s.Header(block)
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("duration", time.Since(start)).Debug("got block header")
+	recordDuration("header", start)
+
+	log.Debug("getting logs")
+	start = time.Now()
+	logs, err := s.getLogs(filter, map[string]interface{}{"blockHash": header.Hash})
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("duration", time.Since(start)).Debugf("got %d logs", len(logs))
+	recordDuration("logs", start)
+
+	return &types.RawData{
+		Block: web3types.Block{BlockHeader: *header},
+		Logs:  logs,
+	}, nil
+}
+
+// ExecLogsRange does the same as ExecLogs for all the blocks in [from, to] but uses a single eth_getLogs call (per
+// filter type) for the whole range; the logs are checked against the headers to make sure they belong to the same chain
+func (s *Scraper) ExecLogsRange(from, to int64, filter types.LogFilter) ([]*types.RawData, error) {
+	var log = s.logger.WithField("blocks", fmt.Sprintf("%d-%d", from, to))
+
+	log.Debug("getting block headers")
+	start := time.Now()
+
+	headers := make([]*web3types.BlockHeader, to-from+1)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for b := from; b <= to; b++ {
+		b := b
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			header, err := s.Header(b)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+
+			headers[b-from] = header
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	log.WithField("duration", time.Since(start)).Debug("got block headers")
+	recordDuration("header", start)
+
+	log.Debug("getting logs")
+	start = time.Now()
+	logs, err := s.getLogs(filter, map[string]interface{}{
+		"fromBlock": "0x" + strconv.FormatInt(from, 16),
+		"toBlock":   "0x" + strconv.FormatInt(to, 16),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("duration", time.Since(start)).Debugf("got %d logs", len(logs))
+	recordDuration("logs", start)
+
+	blocks := make([]*types.RawData, len(headers))
+	for i, h := range headers {
+		blocks[i] = &types.RawData{Block: web3types.Block{BlockHeader: *h}, Logs: []web3types.Log{}}
+	}
+
+	for _, l := range logs {
+		number, err := strconv.ParseInt(l.BlockNumber, 0, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode log block number")
+		}
+
+		if number < from || number > to {
+			return nil, errors.Errorf("got log for block %d outside of the requested range", number)
+		}
+
+		b := blocks[number-from]
+		if !strings.EqualFold(b.Block.Hash, l.BlockHash) {
+			return nil, errors.Errorf("log block hash %s does not match header hash %s for block %d (reorg?)", l.BlockHash, b.Block.Hash, number)
+		}
+
+		b.Logs = append(b.Logs, l)
+	}
+
+	return blocks, nil
+}
+
+// Header returns the header of the block from the node, without the transactions
+func (s *Scraper) Header(block int64) (*web3types.BlockHeader, error) {
+	var header *web3types.BlockHeader
+
+	err := s.conn.MakeRequest(&header, "eth_getBlockByNumber", "0x"+strconv.FormatInt(block, 16), false)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get block header")
+	}
+
+	if header == nil {
+		return nil, errors.Errorf("block %d not found", block)
+	}
+
+	return header, nil
+}
+
+// getLogs runs one eth_getLogs for the contracts in the filter and two for the monitored accounts (as sender and as
+// receiver of erc20 transfers); the results are de-duplicated and sorted
+func (s *Scraper) getLogs(filter types.LogFilter, params map[string]interface{}) ([]web3types.Log, error) {
+	var queries []map[string]interface{}
+
+	withParams := func(q map[string]interface{}) map[string]interface{} {
+		for k, v := range params {
+			q[k] = v
+		}
+
+		return q
+	}
+
+	if len(filter.Addresses) > 0 {
+		queries = append(queries, withParams(map[string]interface{}{"address": filter.Addresses}))
+	}
+
+	if len(filter.Accounts) > 0 {
+		transfer := ethtypes.ERC20.TransferEventID().String()
+
+		var accounts []string
+		for _, a := range filter.Accounts {
+			accounts = append(accounts, "0x000000000000000000000000"+utils.CleanUpHex(a))
+		}
+
+		queries = append(queries,
+			withParams(map[string]interface{}{"topics": []interface{}{transfer, accounts}}),
+			withParams(map[string]interface{}{"topics": []interface{}{transfer, nil, accounts}}),
+		)
+	}
+
+	seen := make(map[string]bool)
+	var logs []web3types.Log
+	for _, q := range queries {
+		var result []web3types.Log
+
+		err := s.conn.MakeRequest(&result, "eth_getLogs", q)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get logs")
+		}
+
+		for _, l := range result {
+			if l.Removed {
+				continue
+			}
+
+			key := l.TransactionHash + l.LogIndex
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			logs = append(logs, l)
+		}
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		bi, _ := strconv.ParseInt(logs[i].BlockNumber, 0, 64)
+		bj, _ := strconv.ParseInt(logs[j].BlockNumber, 0, 64)
+		if bi != bj {
+			return bi < bj
+		}
+
+		li, _ := strconv.ParseInt(logs[i].LogIndex, 0, 64)
+		lj, _ := strconv.ParseInt(logs[j].LogIndex, 0, 64)
+
+		return li < lj
+	})
+
+	return logs, nil
+}
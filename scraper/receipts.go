@@ -0,0 +1,132 @@
+package scraper
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/alethio/web3-go/etherr"
+	web3types "github.com/alethio/web3-go/types"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/types"
+)
+
+// blockReceiptsMethods are tried in order; erigon and openethereum only know the parity one
+var blockReceiptsMethods = []string{"eth_getBlockReceipts", "parity_getBlockReceipts"}
+
+func (s *Scraper) getReceipts(block int64, txs []web3types.Transaction) (types.RawReceipts, error) {
+	if len(txs) == 0 {
+		return nil, nil
+	}
+
+	method, err := s.blockReceiptsMethod(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if method != "" {
+		receipts, err := s.getBlockReceipts(method, block)
+		if err == nil && len(receipts) == len(txs) {
+			return receipts, nil
+		}
+
+		// with multiple endpoints, the one that answered might not support the method even if the others do
+		if err != nil {
+			if _, ok := errors.Cause(err).(*etherr.RpcError); !ok {
+				return nil, err
+			}
+
+			s.logger.WithField("block", block).Warnf("%s; falling back to eth_getTransactionReceipt", err)
+		} else {
+			s.logger.WithField("block", block).Warnf("%s returned %d receipts for %d transactions; falling back to eth_getTransactionReceipt", method, len(receipts), len(txs))
+		}
+	}
+
+	return s.getTransactionReceipts(txs)
+}
+
+// blockReceiptsMethod returns the block receipts method supported by the node, detecting it on the first call
+// an empty string means the node supports none of them and receipts have to be fetched one by one
+func (s *Scraper) blockReceiptsMethod(block int64) (string, error) {
+	s.receiptsMu.Lock()
+	defer s.receiptsMu.Unlock()
+
+	if s.receiptsDetected {
+		return s.receiptsMethod, nil
+	}
+
+	for _, method := range blockReceiptsMethods {
+		_, err := s.getBlockReceipts(method, block)
+		if err == nil {
+			s.logger.Infof("node supports %s; using it to get receipts", method)
+
+			s.receiptsDetected = true
+			s.receiptsMethod = method
+
+			return method, nil
+		}
+
+		// an error returned by the node means the method is not supported; anything else (timeouts, connection
+		// errors) does not tell us anything, so the detection is retried on the next block
+		if _, ok := errors.Cause(err).(*etherr.RpcError); !ok {
+			return "", err
+		}
+	}
+
+	s.logger.Info("node does not support block receipts; using eth_getTransactionReceipt")
+
+	s.receiptsDetected = true
+
+	return "", nil
+}
+
+func (s *Scraper) getBlockReceipts(method string, block int64) (types.RawReceipts, error) {
+	var receipts types.RawReceipts
+
+	err := s.conn.MakeRequest(&receipts, method, "0x"+strconv.FormatInt(block, 16))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get block receipts using %s", method)
+	}
+
+	sort.Sort(receipts)
+
+	return receipts, nil
+}
+
+func (s *Scraper) getTransactionReceipts(txs []web3types.Transaction) (types.RawReceipts, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	var receipts types.RawReceipts
+
+	for _, tx := range txs {
+		wg.Add(1)
+		txCopy := tx
+
+		go func() {
+			defer wg.Done()
+
+			dataReceipt, err := s.conn.GetTransactionReceipt(txCopy.Hash)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+
+			receipts = append(receipts, dataReceipt)
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errors.Wrapf(errs[0], "could not get %d receipts", len(errs))
+	}
+
+	sort.Sort(receipts)
+
+	return receipts, nil
+}
@@ -1,17 +1,15 @@
 package scraper
 
 import (
-	"sort"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/alethio/web3-go/ethrpc/provider/httprpc"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
-	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/eth/rpcpool"
 	"github.com/barnbridge/meminero/types"
 
 	"github.com/alethio/web3-go/ethrpc"
@@ -28,21 +26,19 @@ var (
 type Scraper struct {
 	conn   *ethrpc.ETH
 	logger *logrus.Entry
+
+	receiptsMu       sync.Mutex
+	receiptsDetected bool
+	receiptsMethod   string
 }
 
 func New() (*Scraper, error) {
-	batchLoader, err := httprpc.NewBatchLoader(config.Store.ETH.MaxBatch, 4*time.Millisecond)
-	if err != nil {
-		return nil, errors.Wrap(err, "could not init batch loader")
-	}
-
-	provider, err := httprpc.NewWithLoader(config.Store.ETH.Config.HTTP, batchLoader)
+	pool, err := rpcpool.Init()
 	if err != nil {
-		return nil, errors.Wrap(err, "could not init httprpc provider")
+		return nil, errors.Wrap(err, "could not init rpc pool")
 	}
-	provider.SetHTTPTimeout(5000 * time.Millisecond)
 
-	c, err := ethrpc.New(provider)
+	c, err := ethrpc.New(pool)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not init ethrpc")
 	}
@@ -56,7 +52,8 @@ func New() (*Scraper, error) {
 // Exec does the JSONRPC calls necessary for scraping a given block and returns the raw data
 // It:
 // - scrapes the block using eth_getBlockByNumber
-// - for each transaction in the block, scrapes the receipts using eth_getTransactionReceipt
+// - scrapes all the receipts of the block with a single call if the node supports it (eth_getBlockReceipts or
+// parity_getBlockReceipts), otherwise it scrapes the receipt of each transaction using eth_getTransactionReceipt
 // - for each uncle in the block, scrapes the data using eth_getUncleByBlockHashAndIndex
 func (s *Scraper) Exec(block int64) (*types.RawData, error) {
 	var log = s.logger.WithField("block", block)
@@ -77,34 +74,13 @@ func (s *Scraper) Exec(block int64) (*types.RawData, error) {
 	log.Debug("getting receipts")
 	start = time.Now()
 
-	var wg sync.WaitGroup
-	var errs []error
-	var mu sync.Mutex
-	for _, tx := range dataBlock.Transactions {
-		wg.Add(1)
-		txCopy := tx
-
-		go func() {
-			defer wg.Done()
-
-			dataReceipt, err := s.conn.GetTransactionReceipt(txCopy.Hash)
-			if err != nil {
-				errs = append(errs, err)
-				return
-			}
-
-			mu.Lock()
-			b.Receipts = append(b.Receipts, dataReceipt)
-			mu.Unlock()
-		}()
+	b.Receipts, err = s.getReceipts(block, dataBlock.Transactions)
+	if err != nil {
+		log.Error(err)
+		return nil, err
 	}
-	wg.Wait()
-	sort.Sort(b.Receipts)
 
 	log.WithField("duration", time.Since(start)).Debugf("got %d receipts", len(b.Receipts))
-	if len(errs) > 0 {
-		return nil, errs[0]
-	}
 	recordDuration("receipts", start)
 
 	log.Debug("done scraping block")
@@ -0,0 +1,56 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/utils"
+)
+
+// EventSourceAddresses returns the contracts whose events are stored by the given storable (barn, governance,
+// yieldFarming, erc20Transfers, smartYield, smartYieldRewards, smartAlpha or smartAlphaRewards)
+func (m *Manager) EventSourceAddresses(storable string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var addresses []string
+	s := config.Store.Storable
+
+	switch storable {
+	case "barn":
+		addresses = append(addresses, s.Barn.Address)
+	case "governance":
+		addresses = append(addresses, s.Governance.Address)
+	case "yieldFarming":
+		addresses = append(addresses, s.YieldFarming.Address)
+	case "erc20Transfers":
+		for a := range m.monitoredERC20 {
+			addresses = append(addresses, a)
+		}
+	case "smartYield":
+		for _, p := range m.SmartYield.Pools {
+			addresses = append(addresses, p.PoolAddress, p.ControllerAddress, p.ProviderAddress)
+		}
+	case "smartYieldRewards":
+		for _, p := range m.SmartYield.RewardPools {
+			addresses = append(addresses, p.PoolAddress)
+		}
+	case "smartAlpha":
+		for _, p := range m.SmartAlpha.Pools {
+			addresses = append(addresses, p.PoolAddress)
+		}
+	case "smartAlphaRewards":
+		for _, p := range m.SmartAlpha.RewardPools {
+			addresses = append(addresses, p.PoolAddress)
+		}
+	}
+
+	var result []string
+	for _, a := range addresses {
+		if strings.TrimSpace(a) != "" {
+			result = append(result, utils.NormalizeAddress(strings.TrimSpace(a)))
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,99 @@
+package state
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+// TaskFailure keeps track of the failed attempts of a block
+type TaskFailure struct {
+	Block     int64     `json:"block"`
+	Lane      Lane      `json:"lane"`
+	Attempts  int64     `json:"attempts"`
+	LastError string    `json:"lastError"`
+	FailedAt  time.Time `json:"failedAt"`
+	Dead      bool      `json:"dead"`
+}
+
+// RetryTask records the failure of a task and queues it again in the same lane with an exponential backoff; after
+// `feature.retry.max-attempts` attempts the block is moved to the dead-letter set instead and RetryTask returns true
+func (m *Manager) RetryTask(task Task, taskErr error) (bool, error) {
+	block := task.Block
+
+	f, err := m.queue.Failure(block)
+	if err != nil {
+		return false, errors.Wrap(err, "could not get task failure")
+	}
+
+	if f == nil {
+		f = &TaskFailure{Block: block}
+	}
+
+	f.Lane = task.Lane
+	f.Attempts++
+	f.LastError = taskErr.Error()
+	f.FailedAt = time.Now()
+	f.Dead = config.Store.Feature.Retry.MaxAttempts > 0 && f.Attempts >= config.Store.Feature.Retry.MaxAttempts
+
+	delay := backoff(f.Attempts)
+
+	err = m.queue.Retry(block, *f, delay, f.Dead)
+	if err != nil {
+		return false, errors.Wrap(err, "could not retry task")
+	}
+
+	log := m.logger.WithField("block", block).WithField("attempts", f.Attempts)
+	if f.Dead {
+		log.Error("block failed too many times; moved to the dead-letter queue")
+	} else {
+		log.Warnf("block will be retried in %s", delay)
+	}
+
+	return f.Dead, nil
+}
+
+// TaskFailure returns the failure record of the block, or nil if it did not fail (or it was processed since)
+func (m *Manager) TaskFailure(block int64) (*TaskFailure, error) {
+	return m.queue.Failure(block)
+}
+
+// ClearTaskFailure forgets the failed attempts of a block
+func (m *Manager) ClearTaskFailure(block int64) error {
+	return m.queue.ClearFailure(block)
+}
+
+func (m *Manager) DeadLetters() ([]TaskFailure, error) {
+	return m.queue.DeadLetters()
+}
+
+// RequeueDeadLetter moves a dead-lettered block back to the repair lane of the queue; it returns false if the block was not
+// dead-lettered
+func (m *Manager) RequeueDeadLetter(block int64) (bool, error) {
+	return m.queue.Requeue(block)
+}
+
+// maxBackoff caps the delay between attempts when `feature.retry.max-backoff` is not set, so it can't overflow
+const maxBackoff = 24 * time.Hour
+
+// backoff returns the delay before the next attempt: `feature.retry.backoff` doubled for every failed attempt, up to
+// `feature.retry.max-backoff` (or a day if it's not set)
+func backoff(attempts int64) time.Duration {
+	delay := config.Store.Feature.Retry.Backoff
+	max := config.Store.Feature.Retry.MaxBackoff
+	if max <= 0 || max > maxBackoff {
+		max = maxBackoff
+	}
+
+	for i := int64(1); i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		delay = max
+	}
+
+	return delay
+}
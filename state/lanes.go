@@ -0,0 +1,133 @@
+package state
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+// Lane is a priority class of the task queue
+type Lane string
+
+const (
+	// LaneHead gets the blocks added by the queue keeper while following the chain
+	LaneHead Lane = "head"
+	// LaneRepair gets the blocks queued by the integrity checker and the requeued dead letters
+	LaneRepair Lane = "repair"
+	// LaneBackfill gets the blocks queued manually
+	LaneBackfill Lane = "backfill"
+)
+
+// Lanes are all the lanes of the queue
+var Lanes = []Lane{LaneHead, LaneRepair, LaneBackfill}
+
+// Task is a block taken from the queue together with the lane it was in, so it can be put back in the same lane
+type Task struct {
+	Block int64
+	Lane  Lane
+}
+
+// LaneStats describes the content of a lane
+type LaneStats struct {
+	Lane Lane
+	// Depth is the number of blocks ready to be taken from the lane; the failed ones waiting for their retry are not
+	// counted until their backoff expires
+	Depth int64
+	// Oldest is the lowest ready block in the lane (0 if the lane is empty)
+	Oldest int64
+	// Newest is the highest ready block in the lane (0 if the lane is empty)
+	Newest int64
+}
+
+func ParseLane(s string) (Lane, error) {
+	for _, l := range Lanes {
+		if string(l) == s {
+			return l, nil
+		}
+	}
+
+	return "", errors.Errorf("unknown lane %s (must be one of head, repair, backfill)", s)
+}
+
+// weight returns the configured weight of the lane; the head lane has none since it always goes first
+func (l Lane) weight() int64 {
+	switch l {
+	case LaneRepair:
+		return config.Store.Queue.Weights.Repair
+	case LaneBackfill:
+		return config.Store.Queue.Weights.Backfill
+	default:
+		return 0
+	}
+}
+
+// lanesByPriority returns the lanes in the order they are drained when they are not picked by weight: the head lane
+// first, then the others by descending weight
+func lanesByPriority() []Lane {
+	lanes := append([]Lane{}, Lanes...)
+	sort.SliceStable(lanes, func(i, j int) bool {
+		if lanes[i] == LaneHead || lanes[j] == LaneHead {
+			return lanes[i] == LaneHead
+		}
+
+		return lanes[i].weight() > lanes[j].weight()
+	})
+
+	return lanes
+}
+
+// pickLane chooses the lane the next task is taken from among the non-empty ones; the head lane is always drained
+// first so following the chain never waits for the other lanes, which share the workers according to their weights
+// when it's empty (the chance of a lane being picked is proportional to its weight)
+// lanes with a weight of 0 are only picked when all the others are empty
+func pickLane(nonEmpty []Lane) Lane {
+	var total int64
+	for _, l := range nonEmpty {
+		if l == LaneHead {
+			return l
+		}
+
+		if l.weight() > 0 {
+			total += l.weight()
+		}
+	}
+
+	if total == 0 {
+		return lanesOrdered(nonEmpty)[0]
+	}
+
+	n := rand.Int63n(total)
+	for _, l := range nonEmpty {
+		if l.weight() <= 0 {
+			continue
+		}
+
+		if n < l.weight() {
+			return l
+		}
+
+		n -= l.weight()
+	}
+
+	return nonEmpty[0]
+}
+
+// lanesOrdered returns the given lanes in the same order as lanesByPriority
+func lanesOrdered(lanes []Lane) []Lane {
+	in := make(map[Lane]bool)
+	for _, l := range lanes {
+		in[l] = true
+	}
+
+	var ordered []Lane
+	for _, l := range lanesByPriority() {
+		if in[l] {
+			ordered = append(ordered, l)
+		}
+	}
+
+	return ordered
+}
@@ -0,0 +1,155 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/state"
+)
+
+const (
+	BackendPostgres = "postgres"
+	BackendRedis    = "redis"
+)
+
+var metricsLeader = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "leader_election_leader",
+	Help: "1 if this instance is the leader of the role, 0 otherwise",
+}, []string{"role"})
+
+// session is the leadership of a role held by this instance
+type session interface {
+	// renew keeps the leadership alive; it returns an error once the leadership is lost
+	renew(ctx context.Context) error
+	release()
+}
+
+type backend interface {
+	// campaign tries to become the leader of the role; it returns nil if another instance is the leader
+	campaign(ctx context.Context, role string) (session, error)
+}
+
+// Elector makes sure a role (e.g. the queue keeper) is only performed by one of the instances that run it; the
+// other instances wait and take over when the leader stops or dies
+type Elector struct {
+	backend backend
+	ttl     time.Duration
+	logger  *logrus.Entry
+}
+
+// New creates an elector using the backend from config; the database connection is only used by the postgres backend
+func New(db *pgxpool.Pool) (*Elector, error) {
+	e := &Elector{
+		ttl:    config.Store.Leader.TTL,
+		logger: logrus.WithField("module", "leader"),
+	}
+
+	if e.ttl <= 0 {
+		e.ttl = 15 * time.Second
+	}
+
+	switch config.Store.Leader.Backend {
+	case "", BackendPostgres:
+		if db == nil {
+			return nil, errors.New("the postgres leader election requires a database connection")
+		}
+
+		e.backend = &postgresBackend{db: db}
+	case BackendRedis:
+		r, err := state.NewRedis()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not setup redis connection")
+		}
+
+		e.backend = &redisBackend{redis: r, ttl: e.ttl}
+	default:
+		return nil, errors.Errorf("unknown leader election backend: %s", config.Store.Leader.Backend)
+	}
+
+	return e, nil
+}
+
+// Run campaigns for the role and calls fn while this instance is its leader; the context passed to fn is cancelled
+// when the leadership is lost, and fn must return when that happens so the campaign can start again
+// If leader election is disabled, fn is called right away. Run returns when ctx is done.
+func (e *Elector) Run(ctx context.Context, role string, fn func(ctx context.Context)) {
+	if e == nil || !config.Store.Leader.Enabled {
+		fn(ctx)
+		return
+	}
+
+	log := e.logger.WithField("role", role)
+
+	t := time.NewTicker(e.ttl / 3)
+	defer t.Stop()
+
+	for {
+		s, err := e.backend.campaign(ctx, role)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("could not campaign for leadership: %s", err)
+		}
+
+		if s != nil {
+			log.Info("became the leader")
+			metricsLeader.WithLabelValues(role).Set(1)
+
+			e.lead(ctx, log, s, fn)
+
+			metricsLeader.WithLabelValues(role).Set(0)
+			log.Info("stopped being the leader")
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// lead runs fn until it returns or the leadership is lost, whichever comes first, then gives up the leadership
+func (e *Elector) lead(ctx context.Context, log *logrus.Entry, s session, fn func(ctx context.Context)) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		fn(leaderCtx)
+	}()
+
+	t := time.NewTicker(e.ttl / 3)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if leaderCtx.Err() != nil {
+				// shutting down; waiting for fn to return
+				continue
+			}
+
+			err := s.renew(leaderCtx)
+			if err != nil {
+				log.Errorf("lost leadership: %s", err)
+
+				cancel()
+				<-done
+				s.release()
+
+				return
+			}
+		case <-done:
+			s.release()
+			return
+		}
+	}
+}
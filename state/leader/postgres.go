@@ -0,0 +1,67 @@
+package leader
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// postgresBackend holds the leadership with a session-level advisory lock; the lock belongs to the connection, so it
+// is released by postgres as soon as the leader's connection is gone
+type postgresBackend struct {
+	db *pgxpool.Pool
+}
+
+type postgresSession struct {
+	conn *pgxpool.Conn
+	key  int64
+}
+
+func (b *postgresBackend) campaign(ctx context.Context, role string) (session, error) {
+	conn, err := b.db.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not acquire database connection")
+	}
+
+	key := lockKey(role)
+
+	var acquired bool
+	err = conn.QueryRow(ctx, `select pg_try_advisory_lock($1)`, key).Scan(&acquired)
+	if err != nil {
+		conn.Release()
+		return nil, errors.Wrap(err, "could not try advisory lock")
+	}
+
+	if !acquired {
+		conn.Release()
+		return nil, nil
+	}
+
+	return &postgresSession{conn: conn, key: key}, nil
+}
+
+// renew checks that the connection holding the lock is still alive; session-level advisory locks are only released
+// explicitly or when the connection is closed
+func (s *postgresSession) renew(ctx context.Context) error {
+	err := s.conn.Ping(ctx)
+	if err != nil {
+		return errors.Wrap(err, "lost the connection holding the advisory lock")
+	}
+
+	return nil
+}
+
+// release closes the connection instead of unlocking, so the lock is gone even if the connection is in a bad state
+func (s *postgresSession) release() {
+	_ = s.conn.Conn().Close(context.Background())
+	s.conn.Release()
+}
+
+func lockKey(role string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("meminero:leader:" + role))
+
+	return int64(h.Sum64())
+}
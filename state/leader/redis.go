@@ -0,0 +1,83 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+// renewScript only extends the lease if it's still held by the caller
+var renewScript = redis.NewScript(`
+	if redis.call("get", KEYS[1]) == ARGV[1] then
+		return redis.call("pexpire", KEYS[1], ARGV[2])
+	end
+	return 0
+`)
+
+var releaseScript = redis.NewScript(`
+	if redis.call("get", KEYS[1]) == ARGV[1] then
+		return redis.call("del", KEYS[1])
+	end
+	return 0
+`)
+
+// redisBackend holds the leadership with a key that expires after the ttl unless the leader renews it
+type redisBackend struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+type redisSession struct {
+	backend *redisBackend
+	key     string
+	id      string
+}
+
+func (b *redisBackend) campaign(ctx context.Context, role string) (session, error) {
+	s := &redisSession{
+		backend: b,
+		key:     fmt.Sprintf("%s:leader:%s", config.Store.Redis.List, role),
+		id:      instanceID(),
+	}
+
+	acquired, err := b.redis.SetNX(s.key, s.id, b.ttl).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not acquire leader lease")
+	}
+
+	if !acquired {
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+func (s *redisSession) renew(ctx context.Context) error {
+	renewed, err := renewScript.Run(s.backend.redis, []string{s.key}, s.id, s.backend.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return errors.Wrap(err, "could not renew leader lease")
+	}
+
+	if renewed == 0 {
+		return errors.New("leader lease was taken over")
+	}
+
+	return nil
+}
+
+func (s *redisSession) release() {
+	_ = releaseScript.Run(s.backend.redis, []string{s.key}, s.id).Err()
+}
+
+// instanceID identifies this process in the lease, so it only renews or releases its own
+func instanceID() string {
+	host, _ := os.Hostname()
+
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+}
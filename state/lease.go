@@ -0,0 +1,186 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// heartbeatInterval is how often a lease is renewed; it leaves room for a couple of failed renewals before the lock
+// expires
+const heartbeatInterval = lockTTL / 3
+
+// ErrLockLost is returned when a block is saved by a worker whose lock was taken over by another one
+var ErrLockLost = errors.New("block lock was lost")
+
+var metricsLocksLost = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "scraper_locks_lost",
+	Help: "Number of block locks lost while the block was being processed",
+}, []string{"reason"})
+
+// Lease is a block lock held by this worker; it's renewed in the background until it's released, so a block that
+// takes longer than the lock TTL to process is not picked up by another worker
+// The lock can still be lost (e.g. the queue backend is unreachable for longer than the TTL); in that case the
+// context of the lease is cancelled and the fencing token makes sure the block is not saved anyway (see CheckFence).
+type Lease struct {
+	Block int64
+	Token int64
+
+	queue  Queue
+	logger *logrus.Entry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.Mutex
+	lost bool
+}
+
+// LockBlock tries to acquire the lock of a block and starts renewing it; it returns nil if the block is locked by
+// someone else
+// The context of the lease is derived from ctx and is cancelled if the lock is lost.
+func (m *Manager) LockBlock(ctx context.Context, block int64) (*Lease, error) {
+	token, acquired, err := m.queue.Lock(fmt.Sprint(block))
+	if err != nil {
+		return nil, err
+	}
+
+	if !acquired {
+		return nil, nil
+	}
+
+	l := &Lease{
+		Block:  block,
+		Token:  token,
+		queue:  m.queue,
+		logger: m.logger.WithField("block", block),
+		done:   make(chan struct{}),
+	}
+	l.ctx, l.cancel = context.WithCancel(ctx)
+
+	go l.heartbeat()
+
+	return l, nil
+}
+
+// Context is cancelled when the lease is lost or released
+func (l *Lease) Context() context.Context {
+	return l.ctx
+}
+
+// Lost returns true if the lock was taken over or expired while the block was being processed
+func (l *Lease) Lost() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lost
+}
+
+// Release stops renewing the lock and releases it, unless it was already lost
+func (l *Lease) Release() error {
+	l.cancel()
+	<-l.done
+
+	if l.Lost() {
+		return nil
+	}
+
+	return l.queue.Unlock(fmt.Sprint(l.Block), l.Token)
+}
+
+func (l *Lease) heartbeat() {
+	defer close(l.done)
+
+	t := time.NewTicker(heartbeatInterval)
+	defer t.Stop()
+
+	renewed := time.Now()
+
+	for {
+		select {
+		case <-t.C:
+		case <-l.ctx.Done():
+			return
+		}
+
+		ok, err := l.queue.Extend(fmt.Sprint(l.Block), l.Token)
+		if err != nil {
+			l.logger.Warnf("could not renew block lock: %s", err)
+
+			// the lock might still be there; it's only considered lost once it surely expired
+			if time.Since(renewed) < lockTTL {
+				continue
+			}
+
+			l.markLost("expired")
+			return
+		}
+
+		if !ok {
+			l.markLost("taken")
+			return
+		}
+
+		renewed = time.Now()
+	}
+}
+
+func (l *Lease) markLost(reason string) {
+	l.logger.WithField("reason", reason).Error("lost block lock; aborting")
+	metricsLocksLost.WithLabelValues(reason).Inc()
+
+	l.mu.Lock()
+	l.lost = true
+	l.mu.Unlock()
+
+	l.cancel()
+}
+
+type fencesKey struct{}
+
+// WithFences returns a context that carries the fencing tokens of the leases to the processor, which checks them when
+// it saves the blocks
+func WithFences(ctx context.Context, leases ...*Lease) context.Context {
+	fences := make(map[int64]int64)
+	for _, l := range leases {
+		fences[l.Block] = l.Token
+	}
+
+	return context.WithValue(ctx, fencesKey{}, fences)
+}
+
+// CheckFence records the fencing token of the worker saving a block in the transaction that saves it; it returns
+// ErrLockLost if a worker with a newer token already saved (or rolled back) the block, so the transaction must not be
+// committed
+// blocks without a token in the context were not locked through the queue (e.g. scrape range) and are not checked
+func CheckFence(ctx context.Context, tx pgx.Tx, block int64) error {
+	fences, _ := ctx.Value(fencesKey{}).(map[int64]int64)
+
+	token, exists := fences[block]
+	if !exists {
+		return nil
+	}
+
+	tag, err := tx.Exec(ctx, `
+		insert into public.block_fences (number, token) values ($1, $2)
+		on conflict (number) do update set token = excluded.token where block_fences.token <= excluded.token
+	`, block, token)
+	if err != nil {
+		return errors.Wrap(err, "could not check fencing token")
+	}
+
+	if tag.RowsAffected() == 0 {
+		metricsLocksLost.WithLabelValues("fenced").Inc()
+		return ErrLockLost
+	}
+
+	return nil
+}
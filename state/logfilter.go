@@ -0,0 +1,113 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/types"
+	"github.com/barnbridge/meminero/utils"
+)
+
+// LogFilter builds the filter of all the contracts and accounts the enabled storables look at
+// it must be called after RefreshCache so newly discovered contracts are included
+func (m *Manager) LogFilter() types.LogFilter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	addresses := make(map[string]bool)
+	discovery := make(map[string]bool)
+	add := func(set map[string]bool, addrs ...string) {
+		for _, a := range addrs {
+			if strings.TrimSpace(a) == "" {
+				continue
+			}
+
+			set[utils.NormalizeAddress(strings.TrimSpace(a))] = true
+		}
+	}
+
+	s := config.Store.Storable
+
+	if s.Governance.Enabled {
+		add(addresses, s.Governance.Address)
+	}
+
+	if s.Barn.Enabled {
+		add(addresses, s.Barn.Address)
+	}
+
+	if s.YieldFarming.Enabled {
+		add(addresses, s.YieldFarming.Address)
+	}
+
+	if s.Erc20Transfers.Enabled {
+		for a := range m.monitoredERC20 {
+			add(addresses, a)
+		}
+	}
+
+	if s.SmartYield.Enabled {
+		for _, p := range m.SmartYield.Pools {
+			add(addresses, p.PoolAddress, p.ControllerAddress, p.ProviderAddress, p.SeniorBondAddress, p.JuniorBondAddress)
+		}
+
+		for _, p := range m.SmartYield.RewardPools {
+			add(addresses, p.PoolAddress)
+		}
+
+		factories := strings.Split(s.SmartYield.Rewards.Factories, ",")
+		add(addresses, factories...)
+		add(discovery, factories...)
+	}
+
+	if s.SmartExposure.Enabled {
+		for a := range m.SmartExposure.Pools {
+			add(addresses, a)
+		}
+
+		for a := range m.SmartExposure.Tranches {
+			add(addresses, a)
+		}
+
+		add(addresses, s.SmartExposure.EPoolPeripheryAddress, s.SmartExposure.ETokenFactoryAddress)
+		add(discovery, s.SmartExposure.ETokenFactoryAddress)
+	}
+
+	if s.SmartAlpha.Enabled {
+		for _, p := range m.SmartAlpha.Pools {
+			add(addresses, p.PoolAddress, p.JuniorTokenAddress, p.SeniorTokenAddress)
+		}
+
+		for _, p := range m.SmartAlpha.RewardPools {
+			add(addresses, p.PoolAddress)
+		}
+	}
+
+	if s.ContractEvents.Enabled {
+		for _, c := range s.ContractEvents.Contracts {
+			add(addresses, c.Addresses...)
+		}
+	}
+
+	// the price sources are not monitored by any storable, so their logs are only needed to trigger price snapshots
+	if s.TokenPrices.Enabled && s.TokenPrices.Sampling.Mode == config.SamplingEvents {
+		add(addresses, m.contractStateAddresses("tokenPrices")...)
+	}
+
+	var f types.LogFilter
+	for a := range addresses {
+		f.Addresses = append(f.Addresses, a)
+	}
+
+	for a := range discovery {
+		f.Discovery = append(f.Discovery, a)
+	}
+
+	if s.AccountERC20Transfers.Enabled {
+		for a := range m.monitoredAccounts {
+			f.Accounts = append(f.Accounts, a)
+		}
+	}
+
+	return f
+}
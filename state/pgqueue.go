@@ -0,0 +1,429 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// pollInterval is how often an idle worker checks the postgres queue for new tasks
+const pollInterval = 500 * time.Millisecond
+
+// postgresQueue keeps the tasks in the public.block_tasks table, with a lane column; concurrent workers pop different tasks thanks to
+// `for update skip locked`, and the locks are rows in public.block_locks that expire after lockTTL
+// Failed blocks are queued again with a `not_before` in the future and their failures are kept in
+// public.block_task_failures.
+type postgresQueue struct {
+	db     *pgxpool.Pool
+	logger *logrus.Entry
+}
+
+func newPostgresQueue(db *pgxpool.Pool) *postgresQueue {
+	return &postgresQueue{
+		db:     db,
+		logger: logrus.WithField("module", "state"),
+	}
+}
+
+func (q *postgresQueue) Next(ctx context.Context) (Task, error) {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		task, found, err := q.pop(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Task{}, ctx.Err()
+			}
+
+			return Task{}, errors.Wrap(err, "could not read task from postgres")
+		}
+
+		if found {
+			return task, nil
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return Task{}, ctx.Err()
+		}
+	}
+}
+
+// pop picks one of the lanes that have tasks ready (see pickLane) and removes its lowest block; it returns
+// false if there are no tasks ready
+func (q *postgresQueue) pop(ctx context.Context) (Task, bool, error) {
+	var lanes []string
+	for _, l := range Lanes {
+		lanes = append(lanes, string(l))
+	}
+
+	rows, err := q.db.Query(ctx, `
+		select l
+		from unnest($1::text[]) l
+		where exists(select 1 from public.block_tasks where lane = l and not_before <= now())
+	`, lanes)
+	if err != nil {
+		return Task{}, false, err
+	}
+
+	var nonEmpty []Lane
+	for rows.Next() {
+		var l string
+
+		err := rows.Scan(&l)
+		if err != nil {
+			rows.Close()
+			return Task{}, false, err
+		}
+
+		nonEmpty = append(nonEmpty, Lane(l))
+	}
+	rows.Close()
+
+	if rows.Err() != nil {
+		return Task{}, false, rows.Err()
+	}
+
+	if len(nonEmpty) == 0 {
+		return Task{}, false, nil
+	}
+
+	task := Task{Lane: pickLane(nonEmpty)}
+
+	err = q.db.QueryRow(ctx, `
+		delete from public.block_tasks
+		where number = ( select number
+		                 from public.block_tasks
+		                 where lane = $1 and not_before <= now()
+		                 order by number
+		                 limit 1 for update skip locked )
+		returning number
+	`, task.Lane).Scan(&task.Block)
+	if err == pgx.ErrNoRows {
+		// another worker emptied the lane in the meantime; the next poll picks again
+		return Task{}, false, nil
+	}
+	if err != nil {
+		return Task{}, false, err
+	}
+
+	return task, true, nil
+}
+
+func (q *postgresQueue) Add(lane Lane, block int64) error {
+	_, err := q.db.Exec(context.Background(), `
+		insert into public.block_tasks (number, lane) values ($1, $2) on conflict do nothing
+	`, block, lane)
+	if err != nil {
+		return errors.Wrap(err, "could not add task to postgres")
+	}
+
+	return nil
+}
+
+func (q *postgresQueue) AddBatch(lane Lane, blocks []int64) error {
+	const batchSize = 5000
+
+	for i := 0; i < len(blocks); i += batchSize {
+		end := i + batchSize
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+
+		q.logger.Infof("queueing batch [%d, %d] in lane %s", blocks[i], blocks[end-1], lane)
+
+		_, err := q.db.Exec(context.Background(), `
+			insert into public.block_tasks (number, lane)
+			select unnest($1::bigint[]), $2
+			on conflict do nothing
+		`, blocks[i:end], lane)
+		if err != nil {
+			return errors.Wrap(err, "could not add tasks to postgres")
+		}
+	}
+
+	return nil
+}
+
+func (q *postgresQueue) Remove(lane Lane, block int64) (bool, error) {
+	tag, err := q.db.Exec(context.Background(), `delete from public.block_tasks where number = $1 and lane = $2`, block, lane)
+	if err != nil {
+		return false, errors.Wrap(err, "could not remove task from postgres")
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (q *postgresQueue) Stats() ([]LaneStats, error) {
+	rows, err := q.db.Query(context.Background(), `
+		select lane, count(*), min(number), max(number) from public.block_tasks where not_before <= now() group by lane
+	`)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get lanes stats")
+	}
+	defer rows.Close()
+
+	byLane := make(map[Lane]LaneStats)
+	for rows.Next() {
+		var s LaneStats
+
+		err := rows.Scan(&s.Lane, &s.Depth, &s.Oldest, &s.Newest)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan lane stats")
+		}
+
+		byLane[s.Lane] = s
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "could not get lanes stats")
+	}
+
+	var stats []LaneStats
+	for _, l := range Lanes {
+		s := byLane[l]
+		s.Lane = l
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+func (q *postgresQueue) RemoveRange(lane Lane, from int64, to int64) (int64, error) {
+	tag, err := q.db.Exec(context.Background(), `
+		delete from public.block_tasks where lane = $1 and number between $2 and $3
+	`, lane, from, to)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not remove tasks from postgres")
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (q *postgresQueue) List(lane Lane, from int64, to int64, limit int64) ([]int64, error) {
+	rows, err := q.db.Query(context.Background(), `
+		select number
+		from public.block_tasks
+		where lane = $1 and number between $2 and $3 and not_before <= now()
+		order by number
+		limit $4
+	`, lane, from, to, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list tasks from postgres")
+	}
+	defer rows.Close()
+
+	var blocks []int64
+	for rows.Next() {
+		var b int64
+
+		err := rows.Scan(&b)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan task")
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	return blocks, rows.Err()
+}
+
+// Lock inserts the lock row or takes over an expired one
+func (q *postgresQueue) Lock(key string) (int64, bool, error) {
+	var token int64
+
+	err := q.db.QueryRow(context.Background(), `
+		insert into public.block_locks (key, expires_at, token)
+		values ($1, now() + $2::interval, (extract(epoch from clock_timestamp()) * 1000000)::bigint)
+		on conflict (key) do update set expires_at = excluded.expires_at, token = excluded.token
+		where block_locks.expires_at < now()
+		returning token
+	`, key, lockTTL.String()).Scan(&token)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, errors.Wrap(err, "could not acquire lock")
+	}
+
+	return token, true, nil
+}
+
+func (q *postgresQueue) Extend(key string, token int64) (bool, error) {
+	tag, err := q.db.Exec(context.Background(), `
+		update public.block_locks set expires_at = now() + $3::interval
+		where key = $1 and token = $2 and expires_at >= now()
+	`, key, token, lockTTL.String())
+	if err != nil {
+		return false, errors.Wrap(err, "could not extend lock")
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (q *postgresQueue) Unlock(key string, token int64) error {
+	_, err := q.db.Exec(context.Background(), `delete from public.block_locks where key = $1 and token = $2`, key, token)
+	if err != nil {
+		return errors.Wrap(err, "could not release lock")
+	}
+
+	return nil
+}
+
+func (q *postgresQueue) Locks() ([]LockInfo, error) {
+	rows, err := q.db.Query(context.Background(), `
+		select key, extract(epoch from expires_at - now())::float8 from public.block_locks where expires_at > now() order by key
+	`)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get locks")
+	}
+	defer rows.Close()
+
+	var locks []LockInfo
+	for rows.Next() {
+		var l LockInfo
+		var ttl float64
+
+		err := rows.Scan(&l.Key, &ttl)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan lock")
+		}
+
+		l.TTL = time.Duration(ttl * float64(time.Second))
+		locks = append(locks, l)
+	}
+
+	return locks, rows.Err()
+}
+
+func (q *postgresQueue) Retry(block int64, failure TaskFailure, delay time.Duration, dead bool) error {
+	ctx := context.Background()
+
+	tx, err := q.db.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not start database transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		insert into public.block_task_failures (number, attempts, last_error, failed_at, dead)
+		values ($1, $2, $3, $4, $5)
+		on conflict (number) do update set attempts   = excluded.attempts,
+		                                   last_error = excluded.last_error,
+		                                   failed_at  = excluded.failed_at,
+		                                   dead       = excluded.dead
+	`, block, failure.Attempts, failure.LastError, failure.FailedAt, dead)
+	if err != nil {
+		return errors.Wrap(err, "could not save task failure")
+	}
+
+	if !dead {
+		_, err = tx.Exec(ctx, `
+			insert into public.block_tasks (number, lane, not_before)
+			values ($1, $2, now() + $3::interval)
+			on conflict (number) do update set not_before = excluded.not_before
+		`, block, failure.Lane, delay.String())
+		if err != nil {
+			return errors.Wrap(err, "could not queue task for retry")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(ctx), "could not commit task failure")
+}
+
+func (q *postgresQueue) Failure(block int64) (*TaskFailure, error) {
+	f := TaskFailure{Block: block}
+
+	err := q.db.QueryRow(context.Background(), `
+		select attempts, last_error, failed_at, dead from public.block_task_failures where number = $1
+	`, block).Scan(&f.Attempts, &f.LastError, &f.FailedAt, &f.Dead)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get task failure")
+	}
+
+	return &f, nil
+}
+
+func (q *postgresQueue) ClearFailure(block int64) error {
+	_, err := q.db.Exec(context.Background(), `delete from public.block_task_failures where number = $1`, block)
+	if err != nil {
+		return errors.Wrap(err, "could not clear task failure")
+	}
+
+	return nil
+}
+
+func (q *postgresQueue) DeadLetters() ([]TaskFailure, error) {
+	rows, err := q.db.Query(context.Background(), `
+		select number, attempts, last_error, failed_at from public.block_task_failures where dead order by number
+	`)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query dead-lettered tasks")
+	}
+	defer rows.Close()
+
+	failures := make([]TaskFailure, 0)
+	for rows.Next() {
+		f := TaskFailure{Dead: true}
+
+		err := rows.Scan(&f.Block, &f.Attempts, &f.LastError, &f.FailedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan dead-lettered task")
+		}
+
+		failures = append(failures, f)
+	}
+
+	return failures, rows.Err()
+}
+
+func (q *postgresQueue) Requeue(block int64) (bool, error) {
+	ctx := context.Background()
+
+	tx, err := q.db.Begin(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "could not start database transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `delete from public.block_task_failures where number = $1 and dead`, block)
+	if err != nil {
+		return false, errors.Wrap(err, "could not remove task from dead-letter set")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		insert into public.block_tasks (number, lane) values ($1, $2) on conflict do nothing
+	`, block, LaneRepair)
+	if err != nil {
+		return false, errors.Wrap(err, "could not add task to postgres")
+	}
+
+	return true, errors.Wrap(tx.Commit(ctx), "could not commit requeued task")
+}
+
+func (q *postgresQueue) Reset() error {
+	_, err := q.db.Exec(context.Background(), `truncate public.block_tasks, public.block_task_failures`)
+	if err != nil {
+		return errors.Wrap(err, "could not delete tasks")
+	}
+
+	return nil
+}
+
+// Close does nothing since the database connection is shared with the rest of the app
+func (q *postgresQueue) Close() error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+// lockTTL is how long a lock is held if it's not released explicitly
+const lockTTL = 30 * time.Second
+
+const (
+	QueueBackendRedis    = "redis"
+	QueueBackendPostgres = "postgres"
+)
+
+// Queue is the task queue that distributes the blocks to be scraped between the scraper instances; it also provides
+// the locks used to make sure a block is not processed by two instances at the same time
+type Queue interface {
+	// Next blocks until there's a task in the queue and removes the lowest block from one of the non-empty lanes,
+	// picked according to the lane weights
+	Next(ctx context.Context) (Task, error)
+	Add(lane Lane, block int64) error
+	AddBatch(lane Lane, blocks []int64) error
+	// Remove returns false if the block was not queued in the lane
+	Remove(lane Lane, block int64) (bool, error)
+	// RemoveRange removes the blocks in [from, to] from the lane, including the ones waiting for a retry, and returns
+	// how many were removed
+	RemoveRange(lane Lane, from int64, to int64) (int64, error)
+	// List returns at most `limit` blocks in [from, to] queued in the lane, in ascending order
+	List(lane Lane, from int64, to int64, limit int64) ([]int64, error)
+	// Stats returns the depth and the lowest and highest blocks of every lane
+	Stats() ([]LaneStats, error)
+
+	// Lock tries to acquire the lock identified by key; it returns false if someone else holds it
+	// locks expire automatically after lockTTL, so a crashed instance does not hold them forever
+	// every acquisition gets a fencing token that is higher than all the tokens handed out before it
+	Lock(key string) (int64, bool, error)
+	// Extend renews the lock for another lockTTL; it returns false if the lock is no longer held with the token
+	Extend(key string, token int64) (bool, error)
+	// Unlock releases the lock if it's still held with the token
+	Unlock(key string, token int64) error
+	// Locks returns the locks that are currently held
+	Locks() ([]LockInfo, error)
+
+	// Retry records a failed attempt of the block and queues it again in the lane of the failure once `delay` has
+	// passed, or moves it to the dead-letter set if dead is true
+	Retry(block int64, failure TaskFailure, delay time.Duration, dead bool) error
+	// Failure returns the failure record of a block, or nil if it has none
+	Failure(block int64) (*TaskFailure, error)
+	// ClearFailure removes the failure record of a block after it was processed successfully
+	ClearFailure(block int64) error
+	// DeadLetters returns the failure records of the dead-lettered blocks, ordered by block
+	DeadLetters() ([]TaskFailure, error)
+	// Requeue moves a dead-lettered block back to the repair lane with a fresh attempts counter; it returns false if
+	// the block was not dead-lettered
+	Requeue(block int64) (bool, error)
+
+	// Reset removes all the tasks from the queue
+	Reset() error
+	Close() error
+}
+
+// LockInfo describes a lock that is held
+type LockInfo struct {
+	Key string
+	// TTL is how long until the lock expires if it's not released
+	TTL time.Duration
+}
+
+// NewQueue connects to the queue backend selected in config; the postgres backend needs a database connection
+func NewQueue(db *pgxpool.Pool) (Queue, error) {
+	switch config.Store.Queue.Backend {
+	case "", QueueBackendRedis:
+		r, err := NewRedis()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not setup redis connection")
+		}
+
+		return newRedisQueue(r), nil
+	case QueueBackendPostgres:
+		if db == nil {
+			return nil, errors.New("the postgres queue requires a database connection")
+		}
+
+		return newPostgresQueue(db), nil
+	default:
+		return nil, errors.Errorf("unknown queue backend: %s", config.Store.Queue.Backend)
+	}
+}
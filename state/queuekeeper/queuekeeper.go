@@ -6,6 +6,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/barnbridge/meminero/config"
+	"github.com/barnbridge/meminero/eth"
 	"github.com/barnbridge/meminero/state"
 	"github.com/lacasian/ethwheels/bestblock"
 )
@@ -29,8 +30,13 @@ func New(tracker *bestblock.Tracker, state *state.Manager) (*Keeper, error) {
 }
 
 // Run subscribes to the best block tracker for new blocks and adds tasks to state
+// the blocks are queued up to the finalized height (see eth.FinalizedHeight), or right away in dual finality mode
 func (m *Keeper) Run(ctx context.Context) {
-	skipBlocks := config.Store.Feature.QueueKeeper.Lag
+	var skipBlocks int64
+	if lagMode() {
+		skipBlocks = config.Store.Feature.QueueKeeper.Lag
+	}
+
 	var lastBlock int64
 	var started bool
 
@@ -42,9 +48,15 @@ func (m *Keeper) Run(ctx context.Context) {
 		case b := <-blocks:
 			log := m.logger.WithField("block", b)
 
-			if !started || b-config.Store.Feature.QueueKeeper.Lag <= m.lastBlockAdded {
+			target, err := m.target(b)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+
+			if !started || target <= m.lastBlockAdded {
 				started = true
-				m.lastBlockAdded = b - config.Store.Feature.QueueKeeper.Lag - 1
+				m.lastBlockAdded = target - 1
 			}
 
 			if skipBlocks > 0 {
@@ -58,8 +70,8 @@ func (m *Keeper) Run(ctx context.Context) {
 
 			log.Trace("got new block")
 
-			for i := m.lastBlockAdded + 1; i <= b-config.Store.Feature.QueueKeeper.Lag; i++ {
-				err := m.state.AddTaskToQueue(i)
+			for i := m.lastBlockAdded + 1; i <= target; i++ {
+				err := m.state.AddTaskToQueue(state.LaneHead, i)
 				if err != nil {
 					log.Error(err)
 				} else {
@@ -70,6 +82,23 @@ func (m *Keeper) Run(ctx context.Context) {
 			log.Trace("done adding block to todo")
 		case <-ctx.Done():
 			m.tracker.Unsubscribe(blocks)
+			return
 		}
 	}
 }
+
+// target returns the highest block to queue when b is the best block
+func (m *Keeper) target(b int64) (int64, error) {
+	if config.Store.Feature.Finality.Dual {
+		return b, nil
+	}
+
+	return eth.FinalizedHeight(b)
+}
+
+// lagMode is true if the blocks are queued a fixed number of blocks behind the best block
+func lagMode() bool {
+	mode := config.Store.Feature.Finality.Mode
+
+	return !config.Store.Feature.Finality.Dual && (mode == "" || mode == eth.FinalityLag)
+}
@@ -0,0 +1,591 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/barnbridge/meminero/config"
+)
+
+// fencingTokenScript returns the redis server time in microseconds, bumped above the last token handed out
+// the tokens are timestamps instead of a counter so they keep increasing if the redis data is lost and are comparable
+// to the ones of the postgres backend
+// extendLockScript and unlockScript only touch the lock if it's still held with the caller's token
+var (
+	fencingTokenScript = redis.NewScript(`
+		redis.replicate_commands()
+		local t = redis.call("time")
+		local token = tonumber(t[1]) * 1000000 + tonumber(t[2])
+		local last = tonumber(redis.call("get", KEYS[1]) or "0")
+		if token <= last then
+			token = last + 1
+		end
+		redis.call("set", KEYS[1], token)
+		return token
+	`)
+	extendLockScript = redis.NewScript(`
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			return redis.call("pexpire", KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+	unlockScript = redis.NewScript(`
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			return redis.call("del", KEYS[1])
+		end
+		return 0
+	`)
+)
+
+// redisQueue keeps the tasks in a redis sorted set per lane scored by block number (the backfill lane is `<list>`, the
+// others `<list>:<lane>`); the locks are keys with a TTL
+// Failed blocks wait in `<list>:delayed` (scored by the time they can be retried) and the ones that failed too many
+// times end up in `<list>:dead`; the failure records are kept in the `<list>:failures` hash.
+type redisQueue struct {
+	redis  *redis.Client
+	logger *logrus.Entry
+}
+
+func newRedisQueue(r *redis.Client) *redisQueue {
+	return &redisQueue{
+		redis:  r,
+		logger: logrus.WithField("module", "state"),
+	}
+}
+
+// Next polls the lanes instead of blocking on redis until ctx is done, so a task is never popped after the caller
+// stopped waiting for it (it would be lost); the wait between polls is bounded by the BZPOPMIN timeout
+func (q *redisQueue) Next(ctx context.Context) (Task, error) {
+	for ctx.Err() == nil {
+		err := q.promoteDelayed()
+		if err != nil {
+			return Task{}, errors.Wrap(err, "could not read task from redis")
+		}
+
+		t, found, err := q.pop()
+		if err != nil {
+			return Task{}, errors.Wrap(err, "could not read task from redis")
+		}
+
+		if found {
+			return t, nil
+		}
+
+		// all the lanes are empty; a short timeout so the delayed tasks that become due are promoted while waiting;
+		// the keys are ordered by priority since BZPOPMIN pops from the first non-empty one
+		var keys []string
+		for _, l := range lanesByPriority() {
+			keys = append(keys, q.laneKey(l))
+		}
+
+		res, err := q.redis.BZPopMin(time.Second, keys...).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return Task{}, errors.Wrap(err, "could not read task from redis")
+		}
+
+		t, err = q.task(res.Key, res.Member)
+		if err != nil {
+			return Task{}, errors.Wrap(err, "could not read task from redis")
+		}
+
+		return t, nil
+	}
+
+	return Task{}, ctx.Err()
+}
+
+// pop picks one of the non-empty lanes (see pickLane) and removes its lowest block; it returns false if
+// all the lanes are empty
+func (q *redisQueue) pop() (Task, bool, error) {
+	for {
+		pipe := q.redis.Pipeline()
+
+		cards := make(map[Lane]*redis.IntCmd)
+		for _, l := range Lanes {
+			cards[l] = pipe.ZCard(q.laneKey(l))
+		}
+
+		_, err := pipe.Exec()
+		if err != nil {
+			return Task{}, false, errors.Wrap(err, "could not get lanes size")
+		}
+
+		var nonEmpty []Lane
+		for _, l := range Lanes {
+			if cards[l].Val() > 0 {
+				nonEmpty = append(nonEmpty, l)
+			}
+		}
+
+		if len(nonEmpty) == 0 {
+			return Task{}, false, nil
+		}
+
+		l := pickLane(nonEmpty)
+
+		res, err := q.redis.ZPopMin(q.laneKey(l)).Result()
+		if err != nil && err != redis.Nil {
+			return Task{}, false, err
+		}
+
+		if len(res) == 0 {
+			// another worker emptied the lane in the meantime
+			continue
+		}
+
+		t, err := q.task(q.laneKey(l), res[0].Member)
+		return t, err == nil, err
+	}
+}
+
+// task builds the task popped from the sorted set identified by key
+func (q *redisQueue) task(key string, member interface{}) (Task, error) {
+	block, err := strconv.ParseInt(member.(string), 10, 64)
+	if err != nil {
+		return Task{}, err
+	}
+
+	for _, l := range Lanes {
+		if q.laneKey(l) == key {
+			return Task{Block: block, Lane: l}, nil
+		}
+	}
+
+	return Task{}, errors.Errorf("unknown lane key %s", key)
+}
+
+// promoteDelayed moves the retries whose backoff expired from the delayed set to the queue
+func (q *redisQueue) promoteDelayed() error {
+	due, err := q.redis.ZRangeByScore(q.key("delayed"), redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil {
+		return errors.Wrap(err, "could not get delayed tasks")
+	}
+
+	for _, member := range due {
+		// only the worker that manages to remove the task from the delayed set queues it
+		removed, err := q.redis.ZRem(q.key("delayed"), member).Result()
+		if err != nil {
+			return errors.Wrap(err, "could not remove delayed task")
+		}
+
+		if removed == 0 {
+			continue
+		}
+
+		lane, block, err := parseDelayedMember(member)
+		if err != nil {
+			return errors.Wrap(err, "could not parse delayed task")
+		}
+
+		err = q.Add(lane, block)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Add inserts a block into the redis sorted set of the lane using a ZADD command
+func (q *redisQueue) Add(lane Lane, block int64) error {
+	return q.redis.ZAdd(q.laneKey(lane), redis.Z{
+		Score:  float64(block),
+		Member: block,
+	}).Err()
+}
+
+func (q *redisQueue) AddBatch(lane Lane, blocks []int64) error {
+	var members []redis.Z
+	for _, i := range blocks {
+		members = append(members, redis.Z{
+			Score:  float64(i),
+			Member: i,
+		})
+	}
+
+	const batchSize = 500
+
+	batches := len(blocks)/batchSize + 1
+
+	for i := 0; i < batches; i++ {
+		end := batchSize * (i + 1)
+		if end > len(members) {
+			end = len(members)
+		}
+
+		if batchSize*i >= end {
+			break
+		}
+
+		q.logger.Infof("queueing batch [%d, %d] in lane %s", members[batchSize*i].Member, members[end-1].Member, lane)
+
+		err := q.redis.ZAdd(q.laneKey(lane), members[batchSize*i:end]...).Err()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *redisQueue) Remove(lane Lane, block int64) (bool, error) {
+	removed, err := q.redis.ZRem(q.laneKey(lane), block).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "could not remove task from redis")
+	}
+
+	return removed > 0, nil
+}
+
+func (q *redisQueue) RemoveRange(lane Lane, from int64, to int64) (int64, error) {
+	removed, err := q.redis.ZRemRangeByScore(q.laneKey(lane), strconv.FormatInt(from, 10), strconv.FormatInt(to, 10)).Result()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not remove tasks from redis")
+	}
+
+	// the delayed set is scored by time, so the retries of the lane have to be filtered one by one
+	delayed, err := q.redis.ZRange(q.key("delayed"), 0, -1).Result()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not get delayed tasks")
+	}
+
+	for _, member := range delayed {
+		l, block, err := parseDelayedMember(member)
+		if err != nil {
+			return 0, errors.Wrap(err, "could not parse delayed task")
+		}
+
+		if l != lane || block < from || block > to {
+			continue
+		}
+
+		n, err := q.redis.ZRem(q.key("delayed"), member).Result()
+		if err != nil {
+			return 0, errors.Wrap(err, "could not remove delayed task")
+		}
+
+		removed += n
+	}
+
+	return removed, nil
+}
+
+func (q *redisQueue) List(lane Lane, from int64, to int64, limit int64) ([]int64, error) {
+	members, err := q.redis.ZRangeByScore(q.laneKey(lane), redis.ZRangeBy{
+		Min:   strconv.FormatInt(from, 10),
+		Max:   strconv.FormatInt(to, 10),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list tasks from redis")
+	}
+
+	var blocks []int64
+	for _, m := range members {
+		b, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse task")
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	return blocks, nil
+}
+
+func (q *redisQueue) Lock(key string) (int64, bool, error) {
+	token, err := fencingTokenScript.Run(q.redis, []string{q.key("fence")}).Int64()
+	if err != nil {
+		return 0, false, errors.Wrap(err, "could not get fencing token")
+	}
+
+	acquired, err := q.redis.SetNX(fmt.Sprintf("lock:%s", key), token, lockTTL).Result()
+	if err != nil {
+		return 0, false, errors.Wrap(err, "could not acquire lock")
+	}
+
+	return token, acquired, nil
+}
+
+func (q *redisQueue) Extend(key string, token int64) (bool, error) {
+	extended, err := extendLockScript.Run(q.redis, []string{fmt.Sprintf("lock:%s", key)}, token, lockTTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, errors.Wrap(err, "could not extend lock")
+	}
+
+	return extended == 1, nil
+}
+
+func (q *redisQueue) Unlock(key string, token int64) error {
+	err := unlockScript.Run(q.redis, []string{fmt.Sprintf("lock:%s", key)}, token).Err()
+	if err != nil && err != redis.Nil {
+		return errors.Wrap(err, "could not release lock")
+	}
+
+	return nil
+}
+
+func (q *redisQueue) Locks() ([]LockInfo, error) {
+	var keys []string
+
+	var cursor uint64
+	for {
+		batch, next, err := q.redis.Scan(cursor, "lock:*", 1000).Result()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan locks")
+		}
+
+		keys = append(keys, batch...)
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := q.redis.Pipeline()
+
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, k := range keys {
+		ttls[i] = pipe.PTTL(k)
+	}
+
+	_, err := pipe.Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get locks ttl")
+	}
+
+	var locks []LockInfo
+	for i, k := range keys {
+		// the lock expired between the scan and the ttl check
+		if ttls[i].Val() < 0 {
+			continue
+		}
+
+		locks = append(locks, LockInfo{
+			Key: strings.TrimPrefix(k, "lock:"),
+			TTL: ttls[i].Val(),
+		})
+	}
+
+	return locks, nil
+}
+
+func (q *redisQueue) Retry(block int64, failure TaskFailure, delay time.Duration, dead bool) error {
+	data, err := json.Marshal(failure)
+	if err != nil {
+		return errors.Wrap(err, "could not encode task failure")
+	}
+
+	pipe := q.redis.TxPipeline()
+	pipe.HSet(q.key("failures"), strconv.FormatInt(block, 10), data)
+
+	if dead {
+		pipe.ZAdd(q.key("dead"), redis.Z{Score: float64(block), Member: block})
+	} else {
+		pipe.ZAdd(q.key("delayed"), redis.Z{Score: float64(time.Now().Add(delay).Unix()), Member: delayedMember(failure.Lane, block)})
+	}
+
+	_, err = pipe.Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not save task failure to redis")
+	}
+
+	return nil
+}
+
+func (q *redisQueue) Failure(block int64) (*TaskFailure, error) {
+	data, err := q.redis.HGet(q.key("failures"), strconv.FormatInt(block, 10)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get task failure from redis")
+	}
+
+	var f TaskFailure
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode task failure")
+	}
+
+	return &f, nil
+}
+
+func (q *redisQueue) ClearFailure(block int64) error {
+	pipe := q.redis.TxPipeline()
+	pipe.HDel(q.key("failures"), strconv.FormatInt(block, 10))
+	pipe.ZRem(q.key("dead"), block)
+
+	_, err := pipe.Exec()
+	if err != nil {
+		return errors.Wrap(err, "could not clear task failure from redis")
+	}
+
+	return nil
+}
+
+func (q *redisQueue) DeadLetters() ([]TaskFailure, error) {
+	blocks, err := q.redis.ZRange(q.key("dead"), 0, -1).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get dead-lettered tasks from redis")
+	}
+
+	failures := make([]TaskFailure, 0, len(blocks))
+	if len(blocks) == 0 {
+		return failures, nil
+	}
+
+	values, err := q.redis.HMGet(q.key("failures"), blocks...).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get task failures from redis")
+	}
+
+	for i, v := range values {
+		var f TaskFailure
+
+		data, ok := v.(string)
+		if !ok {
+			// should not happen, but the block is still worth listing
+			f.Block, _ = strconv.ParseInt(blocks[i], 10, 64)
+			f.Dead = true
+		} else {
+			err = json.Unmarshal([]byte(data), &f)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not decode task failure")
+			}
+		}
+
+		failures = append(failures, f)
+	}
+
+	return failures, nil
+}
+
+func (q *redisQueue) Requeue(block int64) (bool, error) {
+	removed, err := q.redis.ZRem(q.key("dead"), block).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "could not remove task from dead-letter set")
+	}
+
+	if removed == 0 {
+		return false, nil
+	}
+
+	err = q.redis.HDel(q.key("failures"), strconv.FormatInt(block, 10)).Err()
+	if err != nil {
+		return false, errors.Wrap(err, "could not clear task failure from redis")
+	}
+
+	return true, q.Add(LaneRepair, block)
+}
+
+func (q *redisQueue) Stats() ([]LaneStats, error) {
+	pipe := q.redis.Pipeline()
+
+	cards := make(map[Lane]*redis.IntCmd)
+	oldest := make(map[Lane]*redis.ZSliceCmd)
+	newest := make(map[Lane]*redis.ZSliceCmd)
+	for _, l := range Lanes {
+		cards[l] = pipe.ZCard(q.laneKey(l))
+		oldest[l] = pipe.ZRangeWithScores(q.laneKey(l), 0, 0)
+		newest[l] = pipe.ZRangeWithScores(q.laneKey(l), -1, -1)
+	}
+
+	_, err := pipe.Exec()
+	if err != nil && err != redis.Nil {
+		return nil, errors.Wrap(err, "could not get lanes stats from redis")
+	}
+
+	var stats []LaneStats
+	for _, l := range Lanes {
+		s := LaneStats{Lane: l, Depth: cards[l].Val()}
+		if o := oldest[l].Val(); len(o) > 0 {
+			s.Oldest = int64(o[0].Score)
+		}
+		if n := newest[l].Val(); len(n) > 0 {
+			s.Newest = int64(n[0].Score)
+		}
+
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+func (q *redisQueue) Reset() error {
+	keys := []string{q.key("delayed"), q.key("dead"), q.key("failures")}
+	for _, l := range Lanes {
+		keys = append(keys, q.laneKey(l))
+	}
+
+	return q.redis.Del(keys...).Err()
+}
+
+func (q *redisQueue) Close() error {
+	return q.redis.Close()
+}
+
+// key returns the name of a redis key that belongs to the queue
+func (q *redisQueue) key(name string) string {
+	return fmt.Sprintf("%s:%s", config.Store.Redis.List, name)
+}
+
+// laneKey returns the name of the sorted set of the lane; the backfill lane keeps using the list itself, so the
+// blocks queued before the lanes were introduced are not lost
+func (q *redisQueue) laneKey(l Lane) string {
+	if l == LaneBackfill {
+		return config.Store.Redis.List
+	}
+
+	return q.key(string(l))
+}
+
+// delayedMember encodes a retry in the delayed set as "<lane>:<block>"
+func delayedMember(l Lane, block int64) string {
+	if l == "" {
+		l = LaneBackfill
+	}
+
+	return fmt.Sprintf("%s:%d", l, block)
+}
+
+// parseDelayedMember decodes a member of the delayed set; the ones without a lane were added before the lanes were
+// introduced and go to the backfill lane
+func parseDelayedMember(member string) (Lane, int64, error) {
+	lane := LaneBackfill
+
+	if i := strings.Index(member, ":"); i >= 0 {
+		l, err := ParseLane(member[:i])
+		if err != nil {
+			return "", 0, err
+		}
+
+		lane, member = l, member[i+1:]
+	}
+
+	block, err := strconv.ParseInt(member, 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return lane, block, nil
+}
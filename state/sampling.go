@@ -0,0 +1,54 @@
+package state
+
+import (
+	"github.com/barnbridge/meminero/utils"
+)
+
+// ContractStateAddresses returns the contracts whose logs trigger a snapshot of the given contract state storable
+// (tokenPrices, smartYield, smartExposure or smartAlpha) when it is sampled on events
+func (m *Manager) ContractStateAddresses(storable string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.contractStateAddresses(storable)
+}
+
+func (m *Manager) contractStateAddresses(storable string) []string {
+	var addresses []string
+
+	switch storable {
+	case "tokenPrices":
+		// the price sources (pairs, pools, aggregators) emit events whenever the price changes
+		for _, t := range m.Tokens {
+			for _, p := range t.Prices {
+				for _, hop := range p.Path {
+					if hop.Address != "" {
+						addresses = append(addresses, hop.Address)
+					}
+				}
+			}
+		}
+	case "smartYield":
+		for _, p := range m.SmartYield.Pools {
+			addresses = append(addresses, p.PoolAddress, p.ControllerAddress, p.ProviderAddress)
+		}
+	case "smartExposure":
+		for a := range m.SmartExposure.Pools {
+			addresses = append(addresses, a)
+		}
+
+		for a := range m.SmartExposure.Tranches {
+			addresses = append(addresses, a)
+		}
+	case "smartAlpha":
+		for _, p := range m.SmartAlpha.Pools {
+			addresses = append(addresses, p.PoolAddress)
+		}
+	}
+
+	for i, a := range addresses {
+		addresses[i] = utils.NormalizeAddress(a)
+	}
+
+	return addresses
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"sync"
 
-	"github.com/go-redis/redis"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -17,7 +16,7 @@ import (
 )
 
 type Manager struct {
-	redis  *redis.Client
+	queue  Queue
 	logger *logrus.Entry
 	db     *pgxpool.Pool
 	mu     *sync.Mutex
@@ -31,10 +30,24 @@ type Manager struct {
 	SmartAlpha    *smartalpha.SmartAlpha
 }
 
-// NewManager instantiates a new task manager and also takes care of the redis connection management
-// it subscribes to the best block tracker for new blocks which it'll add to the redis queue automatically
+// NewManager instantiates a new task manager and also connects to the task queue selected in config
+// the postgres queue uses the database connection, so db can only be nil with the redis queue
 func NewManager(db *pgxpool.Pool) (*Manager, error) {
-	m := &Manager{
+	m := NewManagerWithoutQueue(db)
+
+	var err error
+	m.queue, err = NewQueue(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not setup task queue")
+	}
+
+	return m, nil
+}
+
+// NewManagerWithoutQueue instantiates a manager that only deals with the database cache
+// the task queue and block locks are not available (used for processing block ranges directly)
+func NewManagerWithoutQueue(db *pgxpool.Pool) *Manager {
+	return &Manager{
 		db:            db,
 		logger:        logrus.WithField("module", "state"),
 		mu:            new(sync.Mutex),
@@ -42,14 +55,6 @@ func NewManager(db *pgxpool.Pool) (*Manager, error) {
 		SmartExposure: smartexposure.New(),
 		SmartAlpha:    smartalpha.New(db),
 	}
-
-	var err error
-	m.redis, err = NewRedis()
-	if err != nil {
-		return nil, errors.Wrap(err, "could not setup redis connection")
-	}
-
-	return m, nil
 }
 
 func (m *Manager) RefreshCache(ctx context.Context) error {
@@ -104,6 +109,15 @@ func (m *Manager) RefreshCache(ctx context.Context) error {
 	return nil
 }
 
+// HasQueue returns false if the manager was created without a task queue
+func (m *Manager) HasQueue() bool {
+	return m.queue != nil
+}
+
 func (m *Manager) Close() error {
-	return m.redis.Close()
+	if m.queue == nil {
+		return nil
+	}
+
+	return m.queue.Close()
 }
@@ -2,91 +2,62 @@ package state
 
 import (
 	"context"
-	"strconv"
 	"time"
-
-	"github.com/go-redis/redis"
-	"github.com/pkg/errors"
-
-	"github.com/barnbridge/meminero/config"
 )
 
-func (m *Manager) NextTask(ctx context.Context) (int64, error) {
+func (m *Manager) NextTask(ctx context.Context) (Task, error) {
 	m.logger.Trace("fetching task from state")
 
-	var task int64
-
-	errChan := make(chan error)
-
-	go func() {
-		taskResult, err := m.redis.BZPopMin(0, config.Store.Redis.List).Result()
-		if err != nil {
-			errChan <- err
-			return
-		}
-
-		taskInt, err := strconv.ParseInt(taskResult.Member.(string), 10, 64)
-		if err != nil {
-			errChan <- err
-			return
-		}
-
-		task = taskInt
-		close(errChan)
-	}()
+	task, err := m.queue.Next(ctx)
+	if err != nil {
+		return Task{}, err
+	}
 
-	select {
-	case err := <-errChan:
-		if err != nil {
-			return 0, errors.Wrap(err, "could not read task from redis")
-		}
+	m.logger.Trace("done fetching task")
 
-		m.logger.Trace("done fetching task")
+	return task, nil
+}
 
-		return task, nil
-	case <-ctx.Done():
-		return 0, ctx.Err()
-	}
+// AddTaskToQueue inserts a block into a lane of the task queue
+func (m *Manager) AddTaskToQueue(lane Lane, block int64) error {
+	m.logger.WithField("block", block).WithField("lane", lane).Trace("adding block to todo")
+	return m.queue.Add(lane, block)
 }
 
-// AddTaskToQueue inserts a block into the redis sorted set used for queue management using a ZADD command
-func (m *Manager) AddTaskToQueue(block int64) error {
-	m.logger.WithField("block", block).Trace("adding block to todo")
-	return m.redis.ZAdd(config.Store.Redis.List, redis.Z{
-		Score:  float64(block),
-		Member: block,
-	}).Err()
+// RemoveTask removes a block from a lane of the queue; it returns false if the block was not queued in that lane
+func (m *Manager) RemoveTask(lane Lane, block int64) (bool, error) {
+	return m.queue.Remove(lane, block)
 }
 
-func (m *Manager) AddBatchToQueue(blocks []int64) error {
+func (m *Manager) AddBatchToQueue(lane Lane, blocks []int64) error {
 	start := time.Now()
 
-	var members []redis.Z
-	for _, i := range blocks {
-		members = append(members, redis.Z{
-			Score:  float64(i),
-			Member: i,
-		})
+	err := m.queue.AddBatch(lane, blocks)
+	if err != nil {
+		return err
 	}
 
-	const batchSize = 500
+	m.logger.WithField("duration", time.Since(start)).Info("queued all blocks")
 
-	batches := len(blocks)/batchSize + 1
+	return nil
+}
 
-	for i := 0; i < batches; i++ {
-		end := batchSize * (i + 1)
-		if end > len(members) {
-			end = len(members)
-		}
-		m.logger.Infof("queueing batch [%d, %d]", members[batchSize*i].Member, members[end-1].Member)
+// RemoveTaskRange removes the blocks in [from, to] from a lane of the queue and returns how many were removed
+func (m *Manager) RemoveTaskRange(lane Lane, from int64, to int64) (int64, error) {
+	return m.queue.RemoveRange(lane, from, to)
+}
 
-		err := m.redis.ZAdd(config.Store.Redis.List, members[batchSize*i:end]...).Err()
-		if err != nil && err != redis.Nil {
-			return err
-		}
-	}
+// ListTasks returns at most `limit` blocks in [from, to] queued in the lane
+func (m *Manager) ListTasks(lane Lane, from int64, to int64, limit int64) ([]int64, error) {
+	return m.queue.List(lane, from, to, limit)
+}
 
-	m.logger.WithField("duration", time.Since(start)).Info("queued all blocks")
+// Locks returns the locks that are currently held
+func (m *Manager) Locks() ([]LockInfo, error) {
+	return m.queue.Locks()
+}
 
-	return nil
+// QueueStats returns the depth and the oldest block of every lane of the queue
+func (m *Manager) QueueStats() ([]LaneStats, error) {
+	return m.queue.Stats()
 }
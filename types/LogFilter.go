@@ -0,0 +1,21 @@
+package types
+
+// LogFilter describes the logs that the enabled storables are interested in
+// - Addresses: all the logs emitted by these contracts
+// - Accounts: erc20 Transfer logs of any token, sent or received by these accounts
+// - Discovery: contracts whose events can add new addresses to the filter (factories)
+type LogFilter struct {
+	Addresses []string
+	Accounts  []string
+	Discovery []string
+}
+
+func (f LogFilter) IsDiscovery(addr string) bool {
+	for _, a := range f.Discovery {
+		if a == addr {
+			return true
+		}
+	}
+
+	return false
+}
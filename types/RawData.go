@@ -7,4 +7,8 @@ import (
 type RawData struct {
 	Block    web3types.Block
 	Receipts RawReceipts
+
+	// Logs is only set when the block was scraped in log mode; in that case the block contains only the header
+	// and there are no receipts
+	Logs []web3types.Log
 }
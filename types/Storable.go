@@ -17,3 +17,9 @@ type Storable interface {
 	Result() interface{}
 	ID() string
 }
+
+// Consumer is implemented by the storables that use the Result of other storables (declared in the storable graph of
+// the processor); Consume is called with the results of those storables, by ID, before Execute
+type Consumer interface {
+	Consume(results map[string]interface{})
+}
@@ -1,9 +1,30 @@
 package types
 
+import (
+	"github.com/shopspring/decimal"
+)
+
+const (
+	PriceProviderChainlink = "chainlink"
+	PriceProviderUniswapV2 = "uniswapv2"
+	PriceProviderUniswapV3 = "uniswapv3"
+	PriceProviderCurve     = "curve"
+	PriceProviderFixed     = "fixed"
+)
+
+// PriceProvider is a single hop in a price path; which fields are used depends on the provider of the Price it belongs to
+// - chainlink: address of the aggregator and the decimals of its answer
+// - uniswapv2 / uniswapv3: address of the pair/pool and the decimals of token0 and token1; the price is token0 quoted in token1
+// - curve: address of the pool and the decimals of the virtual price
+// - fixed: only the value
 type PriceProvider struct {
-	Address  string `json:"address"`
-	Reverse  bool   `json:"reverse"`
-	Decimals int64  `json:"decimals"`
+	Address        string           `json:"address"`
+	Reverse        bool             `json:"reverse"`
+	Decimals       int64            `json:"decimals"`
+	Token0Decimals int64            `json:"token0Decimals,omitempty"`
+	Token1Decimals int64            `json:"token1Decimals,omitempty"`
+	TWAPInterval   uint32           `json:"twapInterval,omitempty"`
+	Value          *decimal.Decimal `json:"value,omitempty"`
 }
 
 type Price struct {